@@ -10,7 +10,9 @@ import (
 	"net/http"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	dockerfilters "github.com/docker/docker/pkg/parsers/filters"
@@ -23,6 +25,13 @@ import (
 
 const APIVERSION = "1.16"
 
+// Reservation ratio targeted by a rebalancing when none is given.
+const defaultRebalanceRatio = 0.8
+
+// Header of /containers/create holding the trace of the scheduling decision,
+// when the creation fails or with `decision=1`.
+const decisionHeader = "X-Swarm-Decision"
+
 type context struct {
 	cluster       cluster.Cluster
 	eventsHandler *eventsHandler
@@ -121,6 +130,7 @@ func getContainersJSON(c *context, w http.ResponseWriter, r *http.Request) {
 	out := []*dockerclient.Container{}
 	for _, container := range c.cluster.Containers() {
 		tmp := (*container).Container
+		tmp.Id = container.ClusterID()
 		// Skip stopped containers unless -a was specified.
 		if !strings.Contains(tmp.Status, "Up") && !all {
 			continue
@@ -180,6 +190,9 @@ func getContainerJSON(c *context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// report the cluster-wide ID
+	data = bytes.Replace(data, []byte(fmt.Sprintf("\"Id\":%q", container.Id)), []byte(fmt.Sprintf("\"Id\":%q", container.ClusterID())), 1)
+
 	// insert Node field
 	data = bytes.Replace(data, []byte("\"Name\":\"/"), []byte(fmt.Sprintf("\"Node\":%s,\"Name\":\"/", cluster.SerializeNode(container.Node))), -1)
 
@@ -204,19 +217,29 @@ func postContainersCreate(c *context, w http.ResponseWriter, r *http.Request) {
 	}
 
 	if container := c.cluster.Container(name); container != nil {
-		httpError(w, fmt.Sprintf("Conflict, The name %s is already assigned to %s. You have to delete (or rename) that container to be able to assign %s to a container again.", name, container.Id, name), http.StatusConflict)
+		httpError(w, fmt.Sprintf("Conflict, The name %s is already assigned to %s. You have to delete (or rename) that container to be able to assign %s to a container again.", name, container.ClusterID(), name), http.StatusConflict)
 		return
 	}
 
-	container, err := c.cluster.CreateContainer(&config, name)
+	container, decision, err := c.cluster.CreateContainer(&config, name)
+	if decision != nil && (err != nil || boolValue(r, "decision")) {
+		decision.Explain()
+		if data, err := json.Marshal(decision); err == nil {
+			w.Header().Set(decisionHeader, string(data))
+		}
+	}
 	if err != nil {
+		if decision != nil && decision.Error != "" {
+			httpError(w, fmt.Sprintf("%s\n%s", err.Error(), decision), http.StatusInternalServerError)
+			return
+		}
 		httpError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	fmt.Fprintf(w, "{%q:%q}", "Id", container.Id)
+	fmt.Fprintf(w, "{%q:%q}", "Id", container.ClusterID())
 	return
 }
 
@@ -248,23 +271,38 @@ func postImagesCreate(c *context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	wf := NewWriteFlusher(w)
-
 	if image := r.Form.Get("fromImage"); image != "" { //pull
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-
 		if tag := r.Form.Get("tag"); tag != "" {
 			image += ":" + tag
 		}
-		callback := func(what, status string) {
-			if status == "" {
-				fmt.Fprintf(wf, "{%q:%q,%q:\"Pulling %s...\",%q:{}}", "id", what, "status", image, "progressDetail")
-			} else {
-				fmt.Fprintf(wf, "{%q:%q,%q:\"Pulling %s... : %s\",%q:{}}", "id", what, "status", image, status, "progressDetail")
+
+		var (
+			wf      = NewWriteFlusher(w)
+			encoder = json.NewEncoder(wf)
+			nodes   = make(map[string]bool)
+			failed  = 0
+		)
+		callback := func(progress *cluster.PullProgress) {
+			if len(nodes) == 0 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+			}
+			nodes[progress.Node.ID()] = true
+			if progress.Error != "" {
+				failed++
 			}
+			encoder.Encode(newPullMessage(progress))
+		}
+		if err := c.cluster.Pull(image, r.Form["constraint"], callback); err != nil {
+			httpError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Only report an error, stopping the client, if every node failed.
+		if len(nodes) > 0 && failed == len(nodes) {
+			message := fmt.Sprintf("Unable to pull %s on any node", image)
+			fmt.Fprintf(wf, "{%q:%q,%q:{%q:%q}}\n", "error", message, "errorDetail", "message", message)
 		}
-		c.cluster.Pull(image, callback)
 	} else { //import
 		httpError(w, "Not supported in clustering mode.", http.StatusNotImplemented)
 	}
@@ -324,6 +362,121 @@ func postContainersExec(c *context, w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
+// POST /swarm/rebalance
+func postRebalance(c *context, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	options := &cluster.RebalanceOptions{
+		Ratio:  defaultRebalanceRatio,
+		OptIn:  boolValue(r, "opt-in"),
+		DryRun: boolValue(r, "dry-run"),
+	}
+	if ratio := r.Form.Get("ratio"); ratio != "" {
+		var err error
+		if options.Ratio, err = strconv.ParseFloat(ratio, 64); err != nil {
+			httpError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	moves, err := c.cluster.Rebalance(options)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(moves)
+}
+
+// POST /swarm/nodes/{name:.*}/cordon
+// POST /swarm/nodes/{name:.*}/uncordon
+func postNodeCordon(c *context, w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	cordoned := strings.HasSuffix(r.URL.Path, "/cordon")
+
+	if err := c.cluster.Cordon(name, cordoned); err != nil {
+		if err == cluster.ErrNodeNotFound {
+			httpError(w, fmt.Sprintf("No such node: %s", name), http.StatusNotFound)
+			return
+		}
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /swarm/nodes/{name:.*}/drain
+func postNodeDrain(c *context, w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	moves, err := c.cluster.Drain(name)
+	if err != nil {
+		if err == cluster.ErrNodeNotFound {
+			httpError(w, fmt.Sprintf("No such node: %s", name), http.StatusNotFound)
+			return
+		}
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(moves)
+}
+
+// POST /swarm/schedule
+func postSchedule(c *context, w http.ResponseWriter, r *http.Request) {
+	var config dockerclient.ContainerConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.cluster.Schedule(&config))
+}
+
+// POST /swarm/plan
+func postPlan(c *context, w http.ResponseWriter, r *http.Request) {
+	var requests []*cluster.PlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plan, err := c.cluster.Plan(requests)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+// GET /swarm/nodes
+func getNodes(c *context, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.cluster.NodeStatuses())
+}
+
+// GET /swarm/nodes/{name:.*}
+func getNode(c *context, w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	for _, status := range c.cluster.NodeStatuses() {
+		if status.ID == name || status.Name == name {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(status)
+			return
+		}
+	}
+	httpError(w, fmt.Sprintf("No such node: %s", name), http.StatusNotFound)
+}
+
 // GET /_ping
 func ping(c *context, w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte{'O', 'K'})
@@ -336,6 +489,7 @@ func proxyContainer(c *context, w http.ResponseWriter, r *http.Request) {
 		httpError(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	rewriteContainerPath(r, mux.Vars(r), container)
 
 	if err := proxy(c.tlsConfig, container.Node.Addr(), w, r); err != nil {
 		httpError(w, err.Error(), http.StatusInternalServerError)
@@ -383,6 +537,7 @@ func proxyHijack(c *context, w http.ResponseWriter, r *http.Request) {
 		httpError(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	rewriteContainerPath(r, mux.Vars(r), container)
 
 	if err := hijack(c.tlsConfig, container.Node.Addr(), w, r); err != nil {
 		httpError(w, err.Error(), http.StatusInternalServerError)
@@ -416,12 +571,13 @@ func createRouter(c *context, enableCors bool) *mux.Router {
 			"/_ping":                          ping,
 			"/events":                         getEvents,
 			"/info":                           getInfo,
+			"/metrics":                        getMetrics,
 			"/version":                        getVersion,
 			"/images/json":                    getImagesJSON,
 			"/images/viz":                     notImplementedHandler,
 			"/images/search":                  proxyRandom,
-			"/images/get":                     notImplementedHandler,
-			"/images/{name:.*}/get":           proxyImage,
+			"/images/get":                     getImagesGet,
+			"/images/{name:.*}/get":           getImageGet,
 			"/images/{name:.*}/history":       proxyImage,
 			"/images/{name:.*}/json":          proxyImage,
 			"/containers/ps":                  getContainersJSON,
@@ -434,34 +590,42 @@ func createRouter(c *context, enableCors bool) *mux.Router {
 			"/containers/{name:.*}/stats":     proxyContainer,
 			"/containers/{name:.*}/attach/ws": notImplementedHandler,
 			"/exec/{execid:.*}/json":          proxyContainer,
+			"/swarm/nodes":                    getNodes,
+			"/swarm/nodes/{name:.*}":          getNode,
 		},
 		"POST": {
-			"/auth":                         proxyRandom,
-			"/commit":                       notImplementedHandler,
-			"/build":                        notImplementedHandler,
-			"/images/create":                postImagesCreate,
-			"/images/load":                  notImplementedHandler,
-			"/images/{name:.*}/push":        notImplementedHandler,
-			"/images/{name:.*}/tag":         notImplementedHandler,
-			"/containers/create":            postContainersCreate,
-			"/containers/{name:.*}/kill":    proxyContainer,
-			"/containers/{name:.*}/pause":   proxyContainer,
-			"/containers/{name:.*}/unpause": proxyContainer,
-			"/containers/{name:.*}/rename":  proxyContainer,
-			"/containers/{name:.*}/restart": proxyContainer,
-			"/containers/{name:.*}/start":   proxyContainer,
-			"/containers/{name:.*}/stop":    proxyContainer,
-			"/containers/{name:.*}/wait":    proxyContainer,
-			"/containers/{name:.*}/resize":  proxyContainer,
-			"/containers/{name:.*}/attach":  proxyHijack,
-			"/containers/{name:.*}/copy":    proxyContainer,
-			"/containers/{name:.*}/exec":    postContainersExec,
-			"/exec/{execid:.*}/start":       proxyHijack,
-			"/exec/{execid:.*}/resize":      proxyContainer,
+			"/auth":                           proxyRandom,
+			"/commit":                         notImplementedHandler,
+			"/build":                          postBuild,
+			"/images/create":                  postImagesCreate,
+			"/images/load":                    postImagesLoad,
+			"/images/{name:.*}/push":          postImagesPush,
+			"/images/{name:.*}/tag":           postImagesTag,
+			"/containers/create":              postContainersCreate,
+			"/containers/{name:.*}/kill":      proxyContainer,
+			"/containers/{name:.*}/pause":     proxyContainer,
+			"/containers/{name:.*}/unpause":   proxyContainer,
+			"/containers/{name:.*}/rename":    proxyContainer,
+			"/containers/{name:.*}/restart":   proxyContainer,
+			"/containers/{name:.*}/start":     proxyContainer,
+			"/containers/{name:.*}/stop":      proxyContainer,
+			"/containers/{name:.*}/wait":      proxyContainer,
+			"/containers/{name:.*}/resize":    proxyContainer,
+			"/containers/{name:.*}/attach":    proxyHijack,
+			"/containers/{name:.*}/copy":      proxyContainer,
+			"/containers/{name:.*}/exec":      postContainersExec,
+			"/exec/{execid:.*}/start":         proxyHijack,
+			"/exec/{execid:.*}/resize":        proxyContainer,
+			"/swarm/rebalance":                postRebalance,
+			"/swarm/schedule":                 postSchedule,
+			"/swarm/plan":                     postPlan,
+			"/swarm/nodes/{name:.*}/cordon":   postNodeCordon,
+			"/swarm/nodes/{name:.*}/uncordon": postNodeCordon,
+			"/swarm/nodes/{name:.*}/drain":    postNodeDrain,
 		},
 		"DELETE": {
 			"/containers/{name:.*}": deleteContainers,
-			"/images/{name:.*}":     notImplementedHandler,
+			"/images/{name:.*}":     deleteImages,
 		},
 		"OPTIONS": {
 			"": optionsHandler,
@@ -480,7 +644,10 @@ func createRouter(c *context, enableCors bool) *mux.Router {
 				if enableCors {
 					writeCorsHeaders(w, r)
 				}
+				start := time.Now()
 				localFct(c, w, r)
+				requestsMetric.Inc(r.Method, localRoute)
+				requestDurationMetric.Since(start, r.Method, localRoute)
 			}
 			localMethod := method
 
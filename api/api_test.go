@@ -35,3 +35,19 @@ func TestGetVersion(t *testing.T) {
 	json.NewDecoder(r.Body).Decode(&v)
 	assert.Equal(t, v.Version, "swarm/"+version.VERSION)
 }
+
+func TestRewriteContainerPath(t *testing.T) {
+	container := &cluster.Container{}
+	container.Id = "engine-id"
+
+	req, err := http.NewRequest("POST", "/v1.16/containers/swarm-id/start", nil)
+	assert.NoError(t, err)
+	rewriteContainerPath(req, map[string]string{"name": "swarm-id"}, container)
+	assert.Equal(t, req.URL.Path, "/v1.16/containers/engine-id/start")
+
+	// Exec requests don't refer to the container.
+	req, err = http.NewRequest("POST", "/exec/exec-id/start", nil)
+	assert.NoError(t, err)
+	rewriteContainerPath(req, map[string]string{"execid": "exec-id"}, container)
+	assert.Equal(t, req.URL.Path, "/exec/exec-id/start")
+}
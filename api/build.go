@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+)
+
+// Image label asking to copy the built image to every other healthy node.
+const distributeLabel = "com.docker.swarm.distribute"
+
+// Return whether `key` is the name of a scheduling hint, like `constraint:storage`.
+func isHint(key string) bool {
+	return strings.HasPrefix(key, "constraint:") || strings.HasPrefix(key, "affinity:")
+}
+
+// Extract the scheduling hints from the build args and labels of a build
+// request. The hints are removed from both, so they don't end up in the image
+// and unknown build args don't make the build fail.
+func buildHints(query url.Values) ([]string, url.Values, error) {
+	hints := []string{}
+
+	for _, param := range []string{"buildargs", "labels"} {
+		if query.Get(param) == "" {
+			continue
+		}
+
+		values := make(map[string]string)
+		if err := json.Unmarshal([]byte(query.Get(param)), &values); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %v", param, err)
+		}
+
+		others := make(map[string]string)
+		for key, value := range values {
+			if isHint(key) {
+				// `--build-arg constraint:storage==ssd` splits on the first `=`.
+				hints = append(hints, key+"="+value)
+			} else {
+				others[key] = value
+			}
+		}
+
+		data, err := json.Marshal(others)
+		if err != nil {
+			return nil, nil, err
+		}
+		query.Set(param, string(data))
+	}
+	return hints, query, nil
+}
+
+// Return whether the build asks for its image to be distributed.
+func wantsDistribution(query url.Values) bool {
+	if value := strings.ToLower(query.Get("distribute")); value == "1" || value == "true" {
+		return true
+	}
+
+	labels := make(map[string]string)
+	if err := json.Unmarshal([]byte(query.Get("labels")), &labels); err != nil {
+		return false
+	}
+	return labels[distributeLabel] == "true"
+}
+
+// POST /build
+func postBuild(c *context, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	distribute := wantsDistribution(query)
+
+	hints, query, err := buildHints(query)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.URL.RawQuery = query.Encode()
+
+	n, err := c.cluster.SelectNode(&dockerclient.ContainerConfig{Env: hints})
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.WithFields(log.Fields{"node": n.Name(), "hints": hints}).Debug("Building image")
+
+	client, scheme := newClientAndScheme(c.tlsConfig)
+	defer closeIdleConnections(client)
+
+	// The build context is streamed to the node as is.
+	r.RequestURI = ""
+	r.URL.Scheme = scheme
+	r.URL.Host = n.Addr()
+	resp, err := client.Do(r)
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+
+	copyHeader(w.Header(), resp.Header)
+	w.WriteHeader(resp.StatusCode)
+	wf := NewWriteFlusher(w)
+	io.Copy(wf, resp.Body)
+
+	image := query.Get("t")
+	if resp.StatusCode != http.StatusOK || !distribute || image == "" {
+		return
+	}
+
+	// Build errors are reported in the stream, make sure the image exists.
+	check, err := client.Get(scheme + "://" + n.Addr() + "/images/" + image + "/json")
+	if err != nil {
+		return
+	}
+	check.Body.Close()
+	if check.StatusCode != http.StatusOK {
+		return
+	}
+
+	for _, other := range c.cluster.Nodes() {
+		if other.ID() == n.ID() || !other.IsHealthy() {
+			continue
+		}
+
+		fmt.Fprintf(wf, "{%q:%q}\n", "stream", fmt.Sprintf("Distributing %s to %s\n", image, other.Name()))
+		if err := copyImage(client, scheme, image, n, other); err != nil {
+			log.WithFields(log.Fields{"image": image, "node": other.Name()}).Errorf("Unable to distribute image: %v", err)
+			fmt.Fprintf(wf, "{%q:%q}\n", "stream", fmt.Sprintf("Unable to distribute %s to %s: %v\n", image, other.Name(), err))
+		}
+	}
+}
+
+// Copy `image` from a node to another one through save and load.
+func copyImage(client *http.Client, scheme, image string, from, to cluster.Node) error {
+	saved, err := client.Get(scheme + "://" + from.Addr() + "/images/" + image + "/get")
+	if err != nil {
+		return err
+	}
+	defer saved.Body.Close()
+	if saved.StatusCode != http.StatusOK {
+		return fmt.Errorf("save failed: %s", saved.Status)
+	}
+
+	loaded, err := client.Post(scheme+"://"+to.Addr()+"/images/load", "application/x-tar", saved.Body)
+	if err != nil {
+		return err
+	}
+	defer loaded.Body.Close()
+	io.Copy(ioutil.Discard, loaded.Body)
+	if loaded.StatusCode != http.StatusOK {
+		return fmt.Errorf("load failed: %s", loaded.Status)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildHints(t *testing.T) {
+	query := url.Values{}
+	query.Set("t", "foo")
+	query.Set("buildargs", `{"constraint:storage":"=ssd","HTTP_PROXY":"http://proxy"}`)
+	query.Set("labels", `{"affinity:image":"=redis","maintainer":"me"}`)
+
+	hints, query, err := buildHints(query)
+	assert.NoError(t, err)
+	assert.Equal(t, len(hints), 2)
+	assert.Contains(t, hints, "constraint:storage==ssd")
+	assert.Contains(t, hints, "affinity:image==redis")
+
+	// The hints are removed from the build args and the labels.
+	buildargs := make(map[string]string)
+	assert.NoError(t, json.Unmarshal([]byte(query.Get("buildargs")), &buildargs))
+	assert.Equal(t, buildargs, map[string]string{"HTTP_PROXY": "http://proxy"})
+	assert.Equal(t, query.Get("labels"), `{"maintainer":"me"}`)
+	assert.Equal(t, query.Get("t"), "foo")
+
+	// No hints at all.
+	hints, _, err = buildHints(url.Values{})
+	assert.NoError(t, err)
+	assert.Equal(t, len(hints), 0)
+
+	// Invalid build args.
+	query = url.Values{}
+	query.Set("buildargs", "foo")
+	_, _, err = buildHints(query)
+	assert.Error(t, err)
+}
+
+func TestWantsDistribution(t *testing.T) {
+	assert.False(t, wantsDistribution(url.Values{}))
+	assert.True(t, wantsDistribution(url.Values{"distribute": {"1"}}))
+	assert.True(t, wantsDistribution(url.Values{"labels": {`{"com.docker.swarm.distribute":"true"}`}}))
+	assert.False(t, wantsDistribution(url.Values{"labels": {`{"com.docker.swarm.distribute":"false"}`}}))
+}
@@ -36,12 +36,22 @@ func (eh *eventsHandler) Wait(remoteAddr string) {
 func (eh *eventsHandler) Handle(e *cluster.Event) error {
 	eh.RLock()
 
-	str := fmt.Sprintf("{%q:%q,%q:%q,%q:%q,%q:%d,%q:%s}",
-		"status", e.Status,
-		"id", e.Id,
-		"from", e.From+" node:"+e.Node.Name(),
-		"time", e.Time,
-		"node", cluster.SerializeNode(e.Node))
+	var str string
+	if e.Node != nil {
+		str = fmt.Sprintf("{%q:%q,%q:%q,%q:%q,%q:%d,%q:%s}",
+			"status", e.Status,
+			"id", e.Id,
+			"from", e.From+" node:"+e.Node.Name(),
+			"time", e.Time,
+			"node", cluster.SerializeNode(e.Node))
+	} else {
+		// Events about the manager itself aren't tied to any node.
+		str = fmt.Sprintf("{%q:%q,%q:%q,%q:%q,%q:%d}",
+			"status", e.Status,
+			"id", e.Id,
+			"from", e.From,
+			"time", e.Time)
+	}
 
 	for key, w := range eh.ws {
 		if _, err := fmt.Fprintf(w, str); err != nil {
@@ -33,6 +33,7 @@ func (fn *FakeNode) TotalMemory() int64                    { return 0 }
 func (fn *FakeNode) UsedMemory() int64                     { return 0 }
 func (fn *FakeNode) Labels() map[string]string             { return nil }
 func (fn *FakeNode) IsHealthy() bool                       { return true }
+func (fn *FakeNode) IsCordoned() bool                      { return false }
 
 func TestHandle(t *testing.T) {
 	eh := NewEventsHandler()
@@ -67,3 +68,26 @@ func TestHandle(t *testing.T) {
 
 	assert.Equal(t, str, string(fw.Tmp))
 }
+
+func TestHandleWithoutNode(t *testing.T) {
+	eh := NewEventsHandler()
+
+	fw := &FakeWriter{Tmp: []byte{}}
+	eh.Add("test", fw)
+
+	event := &cluster.Event{}
+	event.Event.Status = "leader_change"
+	event.Event.Id = "manager:2375"
+	event.Event.From = "swarm"
+	event.Event.Time = 0
+
+	assert.NoError(t, eh.Handle(event))
+
+	str := fmt.Sprintf("{%q:%q,%q:%q,%q:%q,%q:%d}",
+		"status", "leader_change",
+		"id", "manager:2375",
+		"from", "swarm",
+		"time", 0)
+
+	assert.Equal(t, str, string(fw.Tmp))
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/gorilla/mux"
+)
+
+// Report the nodes an image operation failed on, nil if it succeeded
+// everywhere.
+func imageFailures(results []*cluster.ImageResult) error {
+	failures := []string{}
+	for _, result := range results {
+		if result.Error != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", result.Node.Name(), result.Error))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Strings(failures)
+	return fmt.Errorf("Failed on %d of %d nodes: %s", len(failures), len(results), strings.Join(failures, "; "))
+}
+
+// Report an error of the cluster about the image `name`.
+func imageError(w http.ResponseWriter, name string, err error) {
+	if err == cluster.ErrImageNotFound {
+		httpError(w, fmt.Sprintf("No such image: %s", name), http.StatusNotFound)
+		return
+	}
+	httpError(w, err.Error(), http.StatusInternalServerError)
+}
+
+// POST /images/{name:.*}/tag
+func postImagesTag(c *context, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name := mux.Vars(r)["name"]
+
+	results, err := c.cluster.TagImage(name, r.Form.Get("repo"), r.Form.Get("tag"), boolValue(r, "force"))
+	if err != nil {
+		imageError(w, name, err)
+		return
+	}
+	if err := imageFailures(results); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// DELETE /images/{name:.*}
+func deleteImages(c *context, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name := mux.Vars(r)["name"]
+
+	results, err := c.cluster.RemoveImage(name, boolValue(r, "force"), boolValue(r, "noprune"))
+	if err != nil {
+		imageError(w, name, err)
+		return
+	}
+	if err := imageFailures(results); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Merge the untagged and deleted images of every node.
+	out := []map[string]string{}
+	for _, result := range results {
+		deleted := []map[string]string{}
+		if err := json.Unmarshal(result.Body, &deleted); err != nil {
+			log.WithField("node", result.Node.Name()).Errorf("Invalid image delete response: %v", err)
+			continue
+		}
+		out = append(out, deleted...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// POST /images/load
+func postImagesLoad(c *context, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Load on every healthy node, or on the ones matching the constraints.
+	results, err := c.cluster.LoadImage(r.Body, r.Form["constraint"])
+	if err == cluster.ErrNodeNotFound {
+		httpError(w, "No node to load the images on", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Only report an error, stopping the client, if every node failed.
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	if failed == len(results) {
+		httpError(w, imageFailures(results).Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	wf := NewWriteFlusher(w)
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(wf, "{%q:%q,%q:{%q:%q}}\n", "error", fmt.Sprintf("%s: %s", result.Node.Name(), result.Error), "errorDetail", "message", result.Error)
+			continue
+		}
+		fmt.Fprintf(wf, "{%q:%q}\n", "stream", fmt.Sprintf("%s: loaded\n", result.Node.Name()))
+		if body := bytes.TrimSpace(result.Body); len(body) > 0 {
+			wf.Write(append(body, '\n'))
+		}
+	}
+}
+
+// POST /images/{name:.*}/push
+func postImagesPush(c *context, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	name := mux.Vars(r)["name"]
+
+	progress, err := c.cluster.PushImage(name, r.Form.Get("tag"), r.Header.Get("X-Registry-Auth"))
+	if err != nil {
+		imageError(w, name, err)
+		return
+	}
+	defer progress.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	io.Copy(NewWriteFlusher(w), progress)
+}
+
+// Send the archive of the images `names`.
+func saveImages(c *context, w http.ResponseWriter, names []string) {
+	archive, err := c.cluster.SaveImages(names)
+	if err == cluster.ErrImageNotFound {
+		httpError(w, fmt.Sprintf("No node holds all the images %s", strings.Join(names, ", ")), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer archive.Close()
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	io.Copy(NewWriteFlusher(w), archive)
+}
+
+// GET /images/{name:.*}/get
+func getImageGet(c *context, w http.ResponseWriter, r *http.Request) {
+	saveImages(c, w, []string{mux.Vars(r)["name"]})
+}
+
+// GET /images/get
+func getImagesGet(c *context, w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	saveImages(c, w, r.Form["names"])
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+// A cluster made of `nodes` only.
+type fakeCluster struct {
+	cluster.Cluster
+	nodes []cluster.Node
+}
+
+func (c *fakeCluster) Nodes() []cluster.Node { return c.nodes }
+
+// A node named `name`.
+type namedNode struct {
+	FakeNode
+	name string
+}
+
+func (n *namedNode) Name() string { return n.name }
+
+// A cluster answering the image operations with `results`, and recording the
+// arguments it was called with.
+type imageCluster struct {
+	cluster.Cluster
+	results []*cluster.ImageResult
+	calls   []string
+}
+
+func (c *imageCluster) TagImage(IdOrName, repo, tag string, force bool) ([]*cluster.ImageResult, error) {
+	c.calls = append(c.calls, "tag "+IdOrName+" "+repo+":"+tag)
+	if IdOrName == "unknown" {
+		return nil, cluster.ErrImageNotFound
+	}
+	return c.results, nil
+}
+
+func (c *imageCluster) RemoveImage(IdOrName string, force, noprune bool) ([]*cluster.ImageResult, error) {
+	c.calls = append(c.calls, "remove "+IdOrName)
+	return c.results, nil
+}
+
+func (c *imageCluster) LoadImage(archive io.Reader, constraints []string) ([]*cluster.ImageResult, error) {
+	data, _ := ioutil.ReadAll(archive)
+	c.calls = append(c.calls, "load "+string(data)+" "+strings.Join(constraints, ","))
+	return c.results, nil
+}
+
+func (c *imageCluster) PushImage(IdOrName, tag, authConfig string) (io.ReadCloser, error) {
+	c.calls = append(c.calls, "push "+IdOrName+":"+tag+" "+authConfig)
+	return ioutil.NopCloser(bytes.NewBufferString(`{"status":"pushed"}`)), nil
+}
+
+func (c *imageCluster) SaveImages(names []string) (io.ReadCloser, error) {
+	return nil, cluster.ErrImageNotFound
+}
+
+// Serve `method path` on `c`.
+func serveImages(t *testing.T, c cluster.Cluster, method, path string, body io.Reader) *httptest.ResponseRecorder {
+	if body == nil {
+		body = &bytes.Buffer{}
+	}
+	r := httptest.NewRecorder()
+	req, err := http.NewRequest(method, path, body)
+	assert.NoError(t, err)
+	req.Header.Set("X-Registry-Auth", "credentials")
+	assert.NoError(t, serveRequest(c, r, req))
+	return r
+}
+
+func TestImages(t *testing.T) {
+	var (
+		node1 = &namedNode{name: "node-1"}
+		node2 = &namedNode{name: "node-2"}
+		c     = &imageCluster{results: []*cluster.ImageResult{
+			{Node: node1, Body: []byte(`[{"Untagged":"foo:latest"},{"Deleted":"foo-id"}]`)},
+			{Node: node2, Body: []byte(`[{"Untagged":"foo:latest"}]`)},
+		}}
+	)
+
+	r := serveImages(t, c, "POST", "/images/foo/tag?repo=bar&tag=1.0", nil)
+	assert.Equal(t, r.Code, http.StatusCreated)
+	assert.Equal(t, c.calls[0], "tag foo bar:1.0")
+
+	r = serveImages(t, c, "POST", "/images/unknown/tag?repo=bar", nil)
+	assert.Equal(t, r.Code, http.StatusNotFound)
+	assert.Equal(t, strings.TrimSpace(r.Body.String()), "No such image: unknown")
+
+	// Delete merges the responses of the nodes.
+	r = serveImages(t, c, "DELETE", "/images/foo", nil)
+	assert.Equal(t, r.Code, http.StatusOK)
+	deleted := []map[string]string{}
+	assert.NoError(t, json.NewDecoder(r.Body).Decode(&deleted))
+	assert.Equal(t, len(deleted), 3)
+
+	r = serveImages(t, c, "POST", "/images/load?constraint=storage==ssd", bytes.NewBufferString("archive"))
+	assert.Equal(t, r.Code, http.StatusOK)
+	assert.Equal(t, c.calls[len(c.calls)-1], "load archive storage==ssd")
+	assert.Contains(t, r.Body.String(), "node-1: loaded")
+	assert.Contains(t, r.Body.String(), "node-2: loaded")
+
+	r = serveImages(t, c, "POST", "/images/foo/push?tag=latest", nil)
+	assert.Equal(t, r.Code, http.StatusOK)
+	assert.Equal(t, c.calls[len(c.calls)-1], "push foo:latest credentials")
+	assert.Equal(t, r.Body.String(), `{"status":"pushed"}`)
+
+	r = serveImages(t, c, "GET", "/images/get?names=foo&names=bar", nil)
+	assert.Equal(t, r.Code, http.StatusNotFound)
+	assert.Equal(t, strings.TrimSpace(r.Body.String()), "No node holds all the images foo, bar")
+
+	// A partial failure is reported with the nodes it failed on.
+	node2Failed := &cluster.ImageResult{Node: node2, Error: "Conflict: image is in use"}
+	c.results = []*cluster.ImageResult{c.results[0], node2Failed}
+	for _, request := range [][2]string{{"POST", "/images/foo/tag?repo=bar"}, {"DELETE", "/images/foo"}} {
+		r = serveImages(t, c, request[0], request[1], nil)
+		assert.Equal(t, r.Code, http.StatusInternalServerError)
+		assert.Equal(t, strings.TrimSpace(r.Body.String()), "Failed on 1 of 2 nodes: node-2: Conflict: image is in use")
+	}
+
+	// Load only fails if every node failed, the others report their errors.
+	r = serveImages(t, c, "POST", "/images/load", bytes.NewBufferString("archive"))
+	assert.Equal(t, r.Code, http.StatusOK)
+	assert.Contains(t, r.Body.String(), `{"error":"node-2: Conflict: image is in use"`)
+
+	c.results = []*cluster.ImageResult{node2Failed}
+	r = serveImages(t, c, "POST", "/images/load", bytes.NewBufferString("archive"))
+	assert.Equal(t, r.Code, http.StatusInternalServerError)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/docker/swarm/metrics"
+)
+
+var (
+	requestsMetric        = metrics.NewCounter("swarm_api_requests_total", "Number of API requests received.", "method", "route")
+	requestDurationMetric = metrics.NewHistogram("swarm_api_request_duration_seconds", "Time spent serving API requests.", metrics.DefBuckets, "method", "route")
+)
+
+// Return the gauges that reflect the current state of the cluster. They are
+// built for every scrape, so that concurrent scrapes don't see each other's.
+func collectMetrics(c *context) *metrics.Registry {
+	var (
+		r                 = &metrics.Registry{}
+		nodesMetric       = r.NewGauge("swarm_nodes", "Number of nodes, by status.", "status")
+		cpusMetric        = r.NewGauge("swarm_node_cpus", "CPUs of a node, after overcommit.", "node")
+		usedCpusMetric    = r.NewGauge("swarm_node_reserved_cpus", "CPUs reserved by the containers of a node.", "node")
+		memoryMetric      = r.NewGauge("swarm_node_memory_bytes", "Memory of a node, after overcommit.", "node")
+		usedMemoryMetric  = r.NewGauge("swarm_node_reserved_memory_bytes", "Memory reserved by the containers of a node.", "node")
+		subscribersMetric = r.NewGauge("swarm_events_subscribers", "Number of clients listening to /events.")
+	)
+
+	counts := map[string]int{"healthy": 0, "unhealthy": 0, "cordoned": 0}
+	for _, n := range c.cluster.Nodes() {
+		switch {
+		case !n.IsHealthy():
+			counts["unhealthy"]++
+		case n.IsCordoned():
+			counts["cordoned"]++
+		default:
+			counts["healthy"]++
+		}
+
+		cpusMetric.Set(float64(n.TotalCpus()), n.Name())
+		usedCpusMetric.Set(float64(n.UsedCpus()), n.Name())
+		memoryMetric.Set(float64(n.TotalMemory()), n.Name())
+		usedMemoryMetric.Set(float64(n.UsedMemory()), n.Name())
+	}
+	for status, count := range counts {
+		nodesMetric.Set(float64(count), status)
+	}
+
+	if c.eventsHandler != nil {
+		subscribersMetric.Set(float64(c.eventsHandler.Size()))
+	}
+	return r
+}
+
+// GET /metrics
+func getMetrics(c *context, w http.ResponseWriter, r *http.Request) {
+	current := collectMetrics(c)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics.Write(w)
+	current.Write(w)
+}
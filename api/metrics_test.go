@@ -0,0 +1,59 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMetrics(t *testing.T) {
+	eh := NewEventsHandler()
+	eh.Add("test", &FakeWriter{})
+	context := &context{
+		cluster:       &fakeCluster{nodes: []cluster.Node{&FakeNode{}}},
+		eventsHandler: eh,
+	}
+	router := createRouter(context, false)
+
+	// The counters are shared by every test, only their increase is checked.
+	scrapes := []float64{}
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/metrics", nil)
+		assert.NoError(t, err)
+		router.ServeHTTP(r, req)
+		assert.Equal(t, r.Code, http.StatusOK)
+		assert.Equal(t, r.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+
+		body := r.Body.String()
+		assert.Contains(t, body, "# TYPE swarm_nodes gauge\n")
+		assert.Contains(t, body, "swarm_nodes{status=\"healthy\"} 1\n")
+		assert.Contains(t, body, "swarm_nodes{status=\"unhealthy\"} 0\n")
+		assert.Contains(t, body, "swarm_node_cpus{node=\"node_name\"} 0\n")
+		assert.Contains(t, body, "swarm_node_reserved_memory_bytes{node=\"node_name\"} 0\n")
+		assert.Contains(t, body, "swarm_events_subscribers 1\n")
+
+		scrapes = append(scrapes, sample(body, `swarm_api_requests_total{method="GET",route="/metrics"}`))
+	}
+
+	// The first scrape shows up in the second one.
+	assert.Equal(t, scrapes[1]-scrapes[0], float64(1))
+}
+
+// Return the value of the sample `name` of `body`, 0 if missing.
+func sample(body, name string) float64 {
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		if value := strings.TrimPrefix(scanner.Text(), name+" "); value != scanner.Text() {
+			v, _ := strconv.ParseFloat(value, 64)
+			return v
+		}
+	}
+	return 0
+}
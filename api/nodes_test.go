@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+// A cluster reporting `statuses`.
+type statusCluster struct {
+	cluster.Cluster
+	statuses []*cluster.NodeStatus
+}
+
+func (c *statusCluster) NodeStatuses() []*cluster.NodeStatus { return c.statuses }
+
+func TestGetNodes(t *testing.T) {
+	c := &statusCluster{statuses: []*cluster.NodeStatus{
+		{ID: "id-1", Name: "node-1", Healthy: true, EngineVersion: "1.6.0", Containers: 2},
+		{ID: "id-2", Name: "node-2", LastError: "connection refused"},
+	}}
+
+	r := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/swarm/nodes", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, serveRequest(c, r, req))
+	assert.Equal(t, r.Code, http.StatusOK)
+
+	var statuses []*cluster.NodeStatus
+	assert.NoError(t, json.NewDecoder(r.Body).Decode(&statuses))
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, statuses[0].Name, "node-1")
+	assert.True(t, statuses[0].Healthy)
+	assert.Equal(t, statuses[0].EngineVersion, "1.6.0")
+	assert.Equal(t, statuses[0].Containers, 2)
+	assert.Equal(t, statuses[1].LastError, "connection refused")
+
+	// Lookup by name and by ID.
+	for _, name := range []string{"node-2", "id-2"} {
+		r = httptest.NewRecorder()
+		req, err = http.NewRequest("GET", "/swarm/nodes/"+name, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, serveRequest(c, r, req))
+		assert.Equal(t, r.Code, http.StatusOK)
+
+		var status cluster.NodeStatus
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&status))
+		assert.Equal(t, status.ID, "id-2")
+	}
+
+	r = httptest.NewRecorder()
+	req, err = http.NewRequest("GET", "/swarm/nodes/unknown", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, serveRequest(c, r, req))
+	assert.Equal(t, r.Code, http.StatusNotFound)
+}
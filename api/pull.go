@@ -0,0 +1,43 @@
+package api
+
+import "github.com/docker/swarm/cluster"
+
+// A pull progress message, as expected by the docker client and tagged with
+// the node it comes from.
+type pullMessage struct {
+	ID             string          `json:"id,omitempty"`
+	Node           string          `json:"node"`
+	Status         string          `json:"status,omitempty"`
+	Progress       string          `json:"progress,omitempty"`
+	ProgressDetail *progressDetail `json:"progressDetail,omitempty"`
+}
+
+type progressDetail struct {
+	Current int64 `json:"current,omitempty"`
+	Total   int64 `json:"total,omitempty"`
+}
+
+// The client shows one line per ID, so the node name is prepended to the ID
+// of the layers. Errors are reported as a status: an error message would make
+// the client stop reading the progress of the other nodes.
+func newPullMessage(progress *cluster.PullProgress) *pullMessage {
+	message := &pullMessage{
+		ID:       progress.Node.Name(),
+		Node:     progress.Node.Name(),
+		Status:   progress.Status,
+		Progress: progress.Progress,
+	}
+	if progress.ID != "" {
+		message.ID += ": " + progress.ID
+	}
+	if progress.Error != "" {
+		message.Status = "Error: " + progress.Error
+	}
+	if progress.ProgressDetail.Current != 0 || progress.ProgressDetail.Total != 0 {
+		message.ProgressDetail = &progressDetail{
+			Current: progress.ProgressDetail.Current,
+			Total:   progress.ProgressDetail.Total,
+		}
+	}
+	return message
+}
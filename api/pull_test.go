@@ -0,0 +1,24 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPullMessage(t *testing.T) {
+	progress := &cluster.PullProgress{Node: &FakeNode{}, ID: "layer", Status: "Downloading"}
+	progress.ProgressDetail.Current = 10
+	progress.ProgressDetail.Total = 20
+
+	data, err := json.Marshal(newPullMessage(progress))
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), `{"id":"node_name: layer","node":"node_name","status":"Downloading","progressDetail":{"current":10,"total":20}}`)
+
+	// Errors are reported as a status.
+	data, err = json.Marshal(newPullMessage(&cluster.PullProgress{Node: &FakeNode{}, Error: "no space left"}))
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), `{"id":"node_name","node":"node_name","status":"Error: no space left"}`)
+}
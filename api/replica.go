@@ -0,0 +1,47 @@
+package api
+
+import (
+	"crypto/tls"
+	"net/http"
+	"regexp"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/leadership"
+)
+
+var (
+	// Requests served by every replica, leader or not.
+	localRoutes = regexp.MustCompile(`^(/v[0-9.]+)?/(_ping|events|metrics)$`)
+
+	// Requests hijacking the connection.
+	hijackRoutes = regexp.MustCompile(`^(/v[0-9.]+)?/(containers/.*/attach|exec/.*/start)$`)
+)
+
+// Serve the requests on the leader only. Replicas forward them to the current
+// leader, or reject them while no leader is elected.
+func replicaHandler(candidate *leadership.Candidate, tlsConfig *tls.Config, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if candidate.IsLeader() || localRoutes.MatchString(r.URL.Path) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		leader := candidate.Leader()
+		if leader == "" {
+			httpError(w, "No elected leader, please try again later", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.WithFields(log.Fields{"method": r.Method, "uri": r.RequestURI, "leader": leader}).Debug("Forwarding request to the leader")
+
+		var err error
+		if hijackRoutes.MatchString(r.URL.Path) {
+			err = hijack(tlsConfig, leader, w, r)
+		} else {
+			err = proxy(tlsConfig, leader, w, r)
+		}
+		if err != nil {
+			httpError(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/swarm/discovery"
+	"github.com/docker/swarm/leadership"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicaHandler(t *testing.T) {
+	local := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("local"))
+	})
+	leaderServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("leader"))
+	}))
+	defer leaderServer.Close()
+
+	locker := discovery.NewMemoryLocker()
+	follower := leadership.NewCandidate(locker, "leader", "follower:2375", time.Minute)
+	handler := replicaHandler(follower, nil, local)
+
+	// Nobody is leading yet.
+	r := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/containers/json", nil)
+	handler.ServeHTTP(r, req)
+	assert.Equal(t, r.Code, http.StatusServiceUnavailable)
+
+	// Elect another leader, then follow it.
+	leaderAddr := strings.TrimPrefix(leaderServer.URL, "http://")
+	locked, err := locker.Lock("leader", leaderAddr, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+	go follower.Run()
+	for follower.Leader() == "" {
+		time.Sleep(time.Millisecond)
+	}
+	assert.False(t, follower.IsLeader())
+
+	// Requests are forwarded to the leader...
+	r = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/containers/json", nil)
+	handler.ServeHTTP(r, req)
+	assert.Equal(t, r.Body.String(), "leader")
+
+	// ...except the ones every replica can serve.
+	for _, path := range []string{"/v1.16/_ping", "/metrics"} {
+		r = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", path, nil)
+		handler.ServeHTTP(r, req)
+		assert.Equal(t, r.Body.String(), "local")
+	}
+}
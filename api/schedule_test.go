@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// A cluster that fails to schedule anything, explaining why.
+type unschedulableCluster struct {
+	cluster.Cluster
+}
+
+var unschedulable = &cluster.Decision{
+	Candidates: []string{"node-1"},
+	Filters: []*cluster.FilterDecision{{
+		Name:     "constraint",
+		Accepted: []string{},
+		Rejected: map[string]string{"node-1": "unable to find a node that satisfies zone==c"},
+		Error:    "unable to find a node that satisfies zone==c",
+	}},
+	Error: "unable to find a node that satisfies zone==c",
+}
+
+func (c *unschedulableCluster) Container(IdOrName string) *cluster.Container { return nil }
+func (c *unschedulableCluster) Schedule(config *dockerclient.ContainerConfig) *cluster.Decision {
+	return unschedulable
+}
+func (c *unschedulableCluster) CreateContainer(config *dockerclient.ContainerConfig, name string) (*cluster.Container, *cluster.Decision, error) {
+	return nil, unschedulable, errors.New(unschedulable.Error)
+}
+
+func TestSchedule(t *testing.T) {
+	c := &unschedulableCluster{}
+
+	r := httptest.NewRecorder()
+	req, err := http.NewRequest("POST", "/swarm/schedule", bytes.NewBufferString(`{"Image":"redis"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, serveRequest(c, r, req))
+	assert.Equal(t, r.Code, http.StatusOK)
+
+	var decision cluster.Decision
+	assert.NoError(t, json.NewDecoder(r.Body).Decode(&decision))
+	assert.Equal(t, decision.Filters[0].Rejected["node-1"], "unable to find a node that satisfies zone==c")
+
+	// The creation fails with the trace in the message and in the header.
+	r = httptest.NewRecorder()
+	req, err = http.NewRequest("POST", "/containers/create", bytes.NewBufferString(`{"Image":"redis"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, serveRequest(c, r, req))
+	assert.Equal(t, r.Code, http.StatusInternalServerError)
+	assert.True(t, strings.Contains(r.Body.String(), "constraint filter: rejected node-1: unable to find a node that satisfies zone==c"))
+
+	decision = cluster.Decision{}
+	assert.NoError(t, json.Unmarshal([]byte(r.Header().Get(decisionHeader)), &decision))
+	assert.Equal(t, decision.Error, unschedulable.Error)
+}
+
+// A cluster creating every container on node-1.
+type scheduledCluster struct {
+	cluster.Cluster
+}
+
+func (c *scheduledCluster) Container(IdOrName string) *cluster.Container { return nil }
+func (c *scheduledCluster) CreateContainer(config *dockerclient.ContainerConfig, name string) (*cluster.Container, *cluster.Decision, error) {
+	container := &cluster.Container{Container: dockerclient.Container{Id: "id"}, Node: &FakeNode{}}
+	return container, &cluster.Decision{Candidates: []string{"node-1"}, Strategy: "spread", Node: "node-1"}, nil
+}
+
+func TestDecisionHeader(t *testing.T) {
+	c := &scheduledCluster{}
+
+	// The decision is only sent on demand when the creation succeeds.
+	r := httptest.NewRecorder()
+	req, err := http.NewRequest("POST", "/containers/create", bytes.NewBufferString(`{"Image":"redis"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, serveRequest(c, r, req))
+	assert.Equal(t, r.Code, http.StatusCreated)
+	assert.Equal(t, r.Header().Get(decisionHeader), "")
+
+	r = httptest.NewRecorder()
+	req, err = http.NewRequest("POST", "/containers/create?decision=1", bytes.NewBufferString(`{"Image":"redis"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, serveRequest(c, r, req))
+	assert.Equal(t, r.Code, http.StatusCreated)
+	decision := cluster.Decision{}
+	assert.NoError(t, json.Unmarshal([]byte(r.Header().Get(decisionHeader)), &decision))
+	assert.Equal(t, decision.Node, "node-1")
+}
+
+// A cluster placing every container on node-1.
+type planCluster struct {
+	cluster.Cluster
+}
+
+func (c *planCluster) Plan(requests []*cluster.PlanRequest) (*cluster.Plan, error) {
+	plan := &cluster.Plan{}
+	for _, request := range requests {
+		if request.Config == nil {
+			return nil, errors.New("invalid plan")
+		}
+		plan.Placements = append(plan.Placements, &cluster.Placement{Name: request.Name, Node: "node-1"})
+	}
+	return plan, nil
+}
+
+func TestPlan(t *testing.T) {
+	c := &planCluster{}
+
+	r := httptest.NewRecorder()
+	req, err := http.NewRequest("POST", "/swarm/plan", bytes.NewBufferString(`[{"Name":"web","Count":1,"Config":{"Image":"nginx"}}]`))
+	assert.NoError(t, err)
+	assert.NoError(t, serveRequest(c, r, req))
+	assert.Equal(t, r.Code, http.StatusOK)
+
+	var plan cluster.Plan
+	assert.NoError(t, json.NewDecoder(r.Body).Decode(&plan))
+	assert.Len(t, plan.Placements, 1)
+	assert.Equal(t, *plan.Placements[0], cluster.Placement{Name: "web", Node: "node-1"})
+
+	r = httptest.NewRecorder()
+	req, err = http.NewRequest("POST", "/swarm/plan", bytes.NewBufferString(`[{"Name":"web"}]`))
+	assert.NoError(t, err)
+	assert.NoError(t, serveRequest(c, r, req))
+	assert.Equal(t, r.Code, http.StatusBadRequest)
+}
@@ -9,6 +9,7 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/leadership"
 )
 
 const DefaultDockerPort = ":2375"
@@ -28,13 +29,17 @@ func newListener(proto, addr string, tlsConfig *tls.Config) (net.Listener, error
 	return l, nil
 }
 
-func ListenAndServe(c cluster.Cluster, hosts []string, enableCors bool, tlsConfig *tls.Config, eventsHandler *eventsHandler) error {
+func ListenAndServe(c cluster.Cluster, hosts []string, enableCors bool, tlsConfig *tls.Config, eventsHandler *eventsHandler, candidate *leadership.Candidate) error {
 	context := &context{
 		cluster:       c,
 		eventsHandler: eventsHandler,
 		tlsConfig:     tlsConfig,
 	}
-	r := createRouter(context, enableCors)
+
+	var r http.Handler = createRouter(context, enableCors)
+	if candidate != nil {
+		r = replicaHandler(candidate, tlsConfig, r)
+	}
 	chErrors := make(chan error, len(hosts))
 
 	for _, host := range hosts {
@@ -41,6 +41,20 @@ func getContainerFromVars(c *context, vars map[string]string) (*cluster.Containe
 	return nil, errors.New("Not found")
 }
 
+// Return the boolean value of the form field `name`, false if unset.
+func boolValue(r *http.Request, name string) bool {
+	value := strings.ToLower(strings.TrimSpace(r.Form.Get(name)))
+	return value == "1" || value == "true" || value == "yes"
+}
+
+// The nodes only know the engine ID, translate the name or cluster-wide ID of
+// the container in the path of the request.
+func rewriteContainerPath(r *http.Request, vars map[string]string, container *cluster.Container) {
+	if name, ok := vars["name"]; ok && name != container.Id {
+		r.URL.Path = strings.Replace(r.URL.Path, "/containers/"+name+"/", "/containers/"+container.Id+"/", 1)
+	}
+}
+
 // from https://github.com/golang/go/blob/master/src/net/http/httputil/reverseproxy.go#L82
 func copyHeader(dst, src http.Header) {
 	for k, vv := range src {
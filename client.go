@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/codegangsta/cli"
+)
+
+// Send a request to the swarm API of the manager given by --host.
+func callManager(c *cli.Context, method, path string, query url.Values) (*http.Response, error) {
+	var (
+		tlsConfig = getTlsConfig(c)
+		client    = &http.Client{}
+		scheme    = "http"
+		addr      = c.String("host")
+	)
+
+	if tlsConfig != nil {
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+		scheme = "https"
+	}
+	if parts := strings.SplitN(addr, "://", 2); len(parts) == 2 {
+		addr = parts[1]
+	}
+
+	u := url.URL{Scheme: scheme, Host: addr, Path: path, RawQuery: query.Encode()}
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
@@ -1,10 +1,20 @@
 package cluster
 
-import "github.com/samalba/dockerclient"
+import (
+	"errors"
+	"io"
+
+	"github.com/samalba/dockerclient"
+)
+
+var (
+	ErrNodeNotFound  = errors.New("node not found")
+	ErrImageNotFound = errors.New("image not found")
+)
 
 type Cluster interface {
 	// Create a container
-	CreateContainer(config *dockerclient.ContainerConfig, name string) (*Container, error)
+	CreateContainer(config *dockerclient.ContainerConfig, name string) (*Container, *Decision, error)
 
 	// Remove a container
 	RemoveContainer(container *Container, force bool) error
@@ -21,11 +31,57 @@ type Cluster interface {
 	// Return container the matching `IdOrName`
 	Container(IdOrName string) *Container
 
-	// Pull images
-	// `callback` can be called multiple time
-	//  `what` is what is being pulled
-	//  `status` is the current status, like "", "in progress" or "downloaded
-	Pull(name string, callback func(what, status string))
+	// Explain where a container would be scheduled, without creating it
+	Schedule(config *dockerclient.ContainerConfig) *Decision
+
+	// Place containers on a simulated copy of the cluster, without creating them
+	Plan(requests []*PlanRequest) (*Plan, error)
+
+	// Return all nodes
+	Nodes() []Node
+
+	// Return the status of every node
+	NodeStatuses() []*NodeStatus
+
+	// Return the node the scheduler chooses for a container with `config`
+	SelectNode(config *dockerclient.ContainerConfig) (Node, error)
+
+	// Pull an image on every healthy node, or on the ones matching
+	// `constraints`. `callback` is called, never concurrently, with the
+	// progress messages of every node.
+	Pull(name string, constraints []string, callback func(progress *PullProgress)) error
+
+	// Tag the image `IdOrName` as `repo:tag` on every healthy node holding
+	// it. Return the result of every node, ErrImageNotFound if none holds it.
+	TagImage(IdOrName, repo, tag string, force bool) ([]*ImageResult, error)
+
+	// Remove the image `IdOrName` from every healthy node holding it. Return
+	// the result of every node, ErrImageNotFound if none holds it.
+	RemoveImage(IdOrName string, force, noprune bool) ([]*ImageResult, error)
+
+	// Load the image archive read from `archive` on every healthy node, or
+	// on the ones matching `constraints`. Return the result of every node.
+	LoadImage(archive io.Reader, constraints []string) ([]*ImageResult, error)
+
+	// Push the image `IdOrName` from a healthy node holding it, with the
+	// encoded registry `authConfig`. Return the progress stream of the
+	// engine, ErrImageNotFound if no node holds the image.
+	PushImage(IdOrName, tag, authConfig string) (io.ReadCloser, error)
+
+	// Return the archive of the images `names`, saved by a healthy node
+	// holding all of them, ErrImageNotFound if there is none.
+	SaveImages(names []string) (io.ReadCloser, error)
+
+	// Move containers off the nodes reserved beyond `options.Ratio`.
+	// Return the moves, planned or done.
+	Rebalance(options *RebalanceOptions) ([]*Move, error)
+
+	// Keep the node matching `IdOrName` out of scheduling, or bring it back.
+	Cordon(IdOrName string, cordoned bool) error
+
+	// Cordon the node matching `IdOrName` and move its containers elsewhere.
+	// Return the moves, including the containers which couldn't move.
+	Drain(IdOrName string) ([]*Move, error)
 
 	// Return some info about the cluster, like nb or containers / images
 	// It is pretty open, so the implementation decides what to return.
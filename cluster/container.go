@@ -7,4 +7,17 @@ type Container struct {
 
 	Info dockerclient.ContainerInfo
 	Node Node
+
+	// SwarmID is the cluster-wide ID of the container, empty if it wasn't
+	// created through swarm.
+	SwarmID string
+}
+
+// Return the ID exposed to the clients: the cluster-wide ID if any, the
+// engine ID otherwise.
+func (c *Container) ClusterID() string {
+	if c.SwarmID != "" {
+		return c.SwarmID
+	}
+	return c.Id
 }
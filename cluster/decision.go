@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The trace of a scheduling decision: the nodes filtered out at every step,
+// the scores given by the strategy and the node it picked.
+type Decision struct {
+	// Names of the nodes considered.
+	Candidates []string
+
+	Filters []*FilterDecision
+
+	Strategy string `json:",omitempty"`
+
+	// Scores of the nodes weighed by the strategy, if it weighs them.
+	Scores map[string]int64 `json:",omitempty"`
+
+	// Name of the node picked by the strategy.
+	Node string `json:",omitempty"`
+
+	// Error is set when no node could be picked.
+	Error string `json:",omitempty"`
+
+	// Fills in why the nodes were rejected. It applies the filters again to
+	// every rejected node, so it only runs when the decision is looked at.
+	explain func()
+}
+
+// Leave the reasons of the rejections to `explain`, until Explain is called.
+func (d *Decision) ExplainWith(explain func()) {
+	d.explain = explain
+}
+
+// Fill in why each filter rejected the nodes it did, if not done already.
+func (d *Decision) Explain() {
+	if d.explain == nil {
+		return
+	}
+	explain := d.explain
+	d.explain = nil
+	explain()
+}
+
+// The outcome of a filter.
+type FilterDecision struct {
+	Name     string
+	Accepted []string
+
+	// Why each rejected node was rejected, by node name, once the decision
+	// was explained.
+	Rejected map[string]string `json:",omitempty"`
+
+	Error string `json:",omitempty"`
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Describe the decision, one step per line.
+func (d *Decision) String() string {
+	lines := []string{fmt.Sprintf("candidates: %s", strings.Join(d.Candidates, ", "))}
+
+	for _, f := range d.Filters {
+		for _, name := range sortedKeys(f.Rejected) {
+			lines = append(lines, fmt.Sprintf("%s filter: rejected %s: %s", f.Name, name, f.Rejected[name]))
+		}
+		lines = append(lines, fmt.Sprintf("%s filter: accepted %s", f.Name, strings.Join(f.Accepted, ", ")))
+	}
+
+	if d.Strategy != "" {
+		names := []string{}
+		for name := range d.Scores {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			lines = append(lines, fmt.Sprintf("%s strategy: scored %s: %d", d.Strategy, name, d.Scores[name]))
+		}
+		if d.Node != "" {
+			lines = append(lines, fmt.Sprintf("%s strategy: picked %s", d.Strategy, d.Node))
+		}
+	}
+
+	if d.Error != "" {
+		lines = append(lines, fmt.Sprintf("error: %s", d.Error))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -7,3 +7,14 @@ type Image struct {
 
 	Node Node
 }
+
+// The outcome of an image operation on a node.
+type ImageResult struct {
+	Node Node
+
+	// Response of the engine, like the deleted images or the load progress.
+	Body []byte
+
+	// Error is set when the operation failed on the node.
+	Error string
+}
@@ -22,6 +22,7 @@ type Node interface {
 	Labels() map[string]string //used by the filters
 
 	IsHealthy() bool
+	IsCordoned() bool //used by the filters
 }
 
 func SerializeNode(node Node) string {
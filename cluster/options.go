@@ -7,4 +7,20 @@ type Options struct {
 	OvercommitRatio float64
 	Discovery       string
 	Heartbeat       int
+	RescheduleGrace int
+
+	// Seconds a node stays in the cluster after its discovery entry
+	// disappeared, 0 to remove it right away.
+	RemoveGrace int
+
+	// Images pulled on the new nodes before they are fully scheduled on.
+	// "state" stands for every image of the requested state.
+	Warmup []string
+
+	// Number of nodes pulling an image at the same time, 0 for no limit.
+	PullParallelism int
+
+	// IsLeader, when set, tells whether this manager leads the replicated
+	// managers. Background tasks changing the cluster only run on the leader.
+	IsLeader func() bool
 }
@@ -0,0 +1,29 @@
+package cluster
+
+import "github.com/samalba/dockerclient"
+
+// Containers to place in a capacity plan.
+type PlanRequest struct {
+	// Name of the containers. When Count is more than 1 the containers are
+	// named Name-1, Name-2 and so on.
+	Name string
+	// Number of containers, 1 when left to 0.
+	Count  int
+	Config *dockerclient.ContainerConfig
+}
+
+// Where a container of a capacity plan would be created.
+type Placement struct {
+	Name string
+	Node string
+}
+
+// The outcome of a capacity plan.
+type Plan struct {
+	Placements []*Placement
+
+	// The first container that could not be placed, and why. Nothing is
+	// placed past it.
+	Failed string `json:",omitempty"`
+	Error  string `json:",omitempty"`
+}
@@ -0,0 +1,19 @@
+package cluster
+
+// A progress message of a pull on a node, as streamed by the engine.
+type PullProgress struct {
+	Node Node
+
+	// ID of the layer, if any.
+	ID       string
+	Status   string
+	Progress string
+
+	ProgressDetail struct {
+		Current int64
+		Total   int64
+	}
+
+	// Error is set when the pull failed on the node.
+	Error string
+}
@@ -0,0 +1,23 @@
+package cluster
+
+// Options of a rebalancing.
+type RebalanceOptions struct {
+	// Highest share of their CPUs or memory the nodes should have reserved.
+	Ratio float64
+
+	// Only move the containers created with the `rebalance:true` hint.
+	// Containers created with `rebalance:false` are never moved.
+	OptIn bool
+
+	// Compute the plan without moving anything.
+	DryRun bool
+}
+
+// A container moved from a node to another one by a rebalancing.
+type Move struct {
+	ID    string
+	Name  string
+	From  string
+	To    string
+	Error string `json:",omitempty"`
+}
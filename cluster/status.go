@@ -0,0 +1,37 @@
+package cluster
+
+import "time"
+
+// The status of a node, as reported by the cluster.
+type NodeStatus struct {
+	ID     string
+	Name   string
+	Addr   string
+	IP     string
+	Labels map[string]string
+
+	Healthy  bool
+	Cordoned bool
+	Warming  bool
+
+	// When the state of the node was last refreshed, and the error if the
+	// refresh failed.
+	LastRefresh time.Time
+	LastError   string
+
+	// Resources of the node, before and after overcommit, and their reservations.
+	Cpus            int64
+	Memory          int64
+	OvercommitRatio float64
+	TotalCpus       int64
+	TotalMemory     int64
+	UsedCpus        int64
+	UsedMemory      int64
+
+	// Share of the containers the node gets from the spread strategy.
+	Weight int64
+
+	Containers    int
+	Images        int
+	EngineVersion string
+}
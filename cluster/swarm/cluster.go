@@ -2,7 +2,9 @@ package swarm
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/docker/pkg/units"
@@ -20,10 +22,33 @@ type Cluster struct {
 	nodes        map[string]*node
 	scheduler    *scheduler.Scheduler
 	options      *cluster.Options
-	store        *state.Store
+	store        state.Store
+
+	// Containers of the store missing from the healthy nodes, and since when.
+	pending map[string]time.Time
+
+	// Containers rescheduled away from an unhealthy node, by engine ID, with
+	// the ID of their node. They are removed if the node ever comes back.
+	stale map[string]string
+
+	// Pending removals of the nodes which left the discovery service, by
+	// address.
+	removals map[string]*time.Timer
+
+	// Latest discovery entries, and the pending connection retries of the
+	// nodes which couldn't be reached, by address.
+	entries map[string]*discovery.Entry
+	retries map[string]*time.Timer
+
+	// Index of the cluster-wide IDs of the store, keyed by engine ID, with
+	// when it was loaded and whether this manager was leading then.
+	ids       map[string]string
+	idsLoaded time.Time
+	idsLeader bool
+	idsLock   sync.Mutex
 }
 
-func NewCluster(scheduler *scheduler.Scheduler, store *state.Store, eventhandler cluster.EventHandler, options *cluster.Options) cluster.Cluster {
+func NewCluster(scheduler *scheduler.Scheduler, store state.Store, eventhandler cluster.EventHandler, options *cluster.Options) cluster.Cluster {
 	log.WithFields(log.Fields{"name": "swarm"}).Debug("Initializing cluster")
 
 	cluster := &Cluster{
@@ -32,6 +57,10 @@ func NewCluster(scheduler *scheduler.Scheduler, store *state.Store, eventhandler
 		scheduler:    scheduler,
 		options:      options,
 		store:        store,
+		pending:      make(map[string]time.Time),
+		stale:        make(map[string]string),
+		removals:     make(map[string]*time.Timer),
+		retries:      make(map[string]*time.Timer),
 	}
 
 	// get the list of entries from the discovery service
@@ -51,11 +80,30 @@ func NewCluster(scheduler *scheduler.Scheduler, store *state.Store, eventhandler
 		go d.Watch(cluster.newEntries)
 	}()
 
+	if options.RescheduleGrace > 0 {
+		go cluster.reconcileLoop()
+	}
+
 	return cluster
 }
 
+// Only the leader of replicated managers changes the cluster on its own.
+func (c *Cluster) isLeader() bool {
+	return c.options == nil || c.options.IsLeader == nil || c.options.IsLeader()
+}
+
 // callback for the events
 func (c *Cluster) Handle(e *cluster.Event) error {
+	// Report the cluster-wide ID of the containers.
+	if e.Id != "" {
+		id, exists := c.swarmIDs()[e.Id]
+		if !exists && c.reloadIDs() {
+			id, exists = c.swarmIDs()[e.Id]
+		}
+		if exists {
+			e.Id = id
+		}
+	}
 	if err := c.eventHandler.Handle(e); err != nil {
 		log.Error(err)
 	}
@@ -63,31 +111,57 @@ func (c *Cluster) Handle(e *cluster.Event) error {
 }
 
 // Schedule a brand new container into the cluster.
-func (c *Cluster) CreateContainer(config *dockerclient.ContainerConfig, name string) (*cluster.Container, error) {
+func (c *Cluster) CreateContainer(config *dockerclient.ContainerConfig, name string) (*cluster.Container, *cluster.Decision, error) {
 
 	c.RLock()
 	defer c.RUnlock()
 
-	n, err := c.scheduler.SelectNodeForContainer(c.listNodes(), config)
+	if schedulingMode(config) == state.ModeGlobal {
+		return c.createGlobalContainer(config, name)
+	}
+
+	n, decision, err := c.schedule(c.Nodes(), config)
 	if err != nil {
-		return nil, err
+		return nil, decision, err
 	}
 
 	if nn, ok := n.(*node); ok {
+		swarmID, err := newSwarmID()
+		if err != nil {
+			return nil, decision, err
+		}
+
 		container, err := nn.create(config, name, true)
 		if err != nil {
-			return nil, err
+			return nil, decision, err
 		}
+		container = withSwarmID(container, swarmID)
 
 		st := &state.RequestedState{
-			ID:     container.Id,
-			Name:   name,
-			Config: config,
+			ID:       swarmID,
+			EngineID: container.Id,
+			Name:     name,
+			Config:   config,
 		}
-		return container, c.store.Add(container.Id, st)
+		return container, decision, c.addState(st)
 	}
 
-	return nil, nil
+	return nil, decision, nil
+}
+
+// Explain where a container would be scheduled, without creating it.
+func (c *Cluster) Schedule(config *dockerclient.ContainerConfig) *cluster.Decision {
+	c.RLock()
+	defer c.RUnlock()
+
+	dryRun := c.scheduler.DryRun()
+	if schedulingMode(config) == state.ModeGlobal {
+		_, decision, _ := dryRun.Filter(c.Nodes(), config)
+		return decision
+	}
+
+	_, decision, _ := c.scheduleWith(dryRun, c.Nodes(), config)
+	return decision
 }
 
 // Remove a container from the cluster. Containers should always be destroyed
@@ -102,8 +176,16 @@ func (c *Cluster) RemoveContainer(container *cluster.Container, force bool) erro
 		}
 	}
 
-	if err := c.store.Remove(container.Id); err != nil {
-		if err == state.ErrNotFound {
+	key := container.SwarmID
+	if key == "" {
+		key = c.swarmIDs()[container.Id]
+	}
+	// Containers not created through swarm aren't in the store.
+	if key == "" {
+		return nil
+	}
+	if err := c.removeState(key); err != nil {
+		if err == state.ErrNotFound || err == state.ErrInvalidKey {
 			log.Debugf("Container %s not found in the store", container.Id)
 			return nil
 		}
@@ -112,40 +194,131 @@ func (c *Cluster) RemoveContainer(container *cluster.Container, force bool) erro
 	return nil
 }
 
-// Entries are Docker Nodes
+// Entries are Docker Nodes. Every call lists all of them: the nodes missing
+// from `entries` are removed after the grace period.
 func (c *Cluster) newEntries(entries []*discovery.Entry) {
+	addrs := make(map[string]*discovery.Entry)
 	for _, entry := range entries {
-		go func(m *discovery.Entry) {
-			if c.getNode(m.String()) == nil {
-				n := NewNode(m.String(), c.options.OvercommitRatio)
-				if err := n.connect(c.options.TLSConfig); err != nil {
-					log.Error(err)
-					return
-				}
-				c.Lock()
-
-				if old, exists := c.nodes[n.id]; exists {
-					c.Unlock()
-					if old.ip != n.ip {
-						log.Errorf("ID duplicated. %s shared by %s and %s", n.id, old.IP(), n.IP())
-					} else {
-						log.Errorf("node %q is already registered", n.id)
-					}
-					return
-				}
-				c.nodes[n.id] = n
-				if err := n.events(c); err != nil {
-					log.Error(err)
-					c.Unlock()
-					return
-				}
-				c.Unlock()
+		addrs[entry.String()] = entry
+	}
 
+	c.Lock()
+	c.entries = addrs
+	for _, n := range c.nodes {
+		if addrs[n.addr] != nil {
+			if timer, exists := c.removals[n.addr]; exists {
+				log.WithFields(log.Fields{"name": n.name, "id": n.id}).Info("Node is back in the discovery service")
+				timer.Stop()
+				delete(c.removals, n.addr)
 			}
-		}(entry)
+			continue
+		}
+		if _, exists := c.removals[n.addr]; exists {
+			continue
+		}
+		log.WithFields(log.Fields{"name": n.name, "id": n.id}).Infof("Node left the discovery service, removing it in %ds", c.options.RemoveGrace)
+		addr := n.addr
+		c.removals[addr] = time.AfterFunc(time.Duration(c.options.RemoveGrace)*time.Second, func() { c.removeNode(addr) })
+	}
+	c.Unlock()
+
+	for _, entry := range entries {
+		go c.addEntry(entry)
+	}
+}
+
+// Update the node of the discovery entry `m`, or connect to it if it's new.
+func (c *Cluster) addEntry(m *discovery.Entry) {
+	c.RLock()
+	n := c.getNode(m.String())
+	_, retrying := c.retries[m.String()]
+	c.RUnlock()
+
+	if n != nil {
+		n.setEntry(m)
+		return
+	}
+	// The retry picks up the latest entry.
+	if retrying {
+		return
+	}
+
+	n = NewNode(m.String(), c.options.OvercommitRatio)
+	n.setEntry(m)
+	if err := n.connect(withServerName(c.options.TLSConfig, m.TLSServerName)); err != nil {
+		log.Error(err)
+		c.retryEntry(m.String())
+		return
+	}
+	c.Lock()
+
+	if old, exists := c.nodes[n.id]; exists {
+		c.Unlock()
+		if old.ip != n.ip {
+			log.Errorf("ID duplicated. %s shared by %s and %s", n.id, old.IP(), n.IP())
+		} else {
+			log.Errorf("node %q is already registered", n.id)
+		}
+		return
+	}
+	c.nodes[n.id] = n
+	if err := n.events(c); err != nil {
+		log.Error(err)
+		c.Unlock()
+		return
+	}
+	c.Unlock()
+
+	if c.isLeader() {
+		c.startGlobalContainers(n)
+		go c.warmup(n)
 	}
 }
 
+// Connect to the node at `addr` again after a heartbeat, as long as it's
+// listed by the discovery service.
+func (c *Cluster) retryEntry(addr string) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.entries[addr] == nil {
+		return
+	}
+	log.WithField("addr", addr).Debugf("Retrying to connect to the node in %ds", c.options.Heartbeat)
+	c.retries[addr] = time.AfterFunc(time.Duration(c.options.Heartbeat)*time.Second, func() {
+		c.Lock()
+		m := c.entries[addr]
+		delete(c.retries, addr)
+		c.Unlock()
+
+		if m != nil {
+			c.addEntry(m)
+		}
+	})
+}
+
+// Remove the node at `addr`, unless it came back in the meantime.
+func (c *Cluster) removeNode(addr string) {
+	c.Lock()
+	if _, exists := c.removals[addr]; !exists {
+		c.Unlock()
+		return
+	}
+	delete(c.removals, addr)
+	n := c.getNode(addr)
+	if n == nil {
+		c.Unlock()
+		return
+	}
+	delete(c.nodes, n.id)
+	c.Unlock()
+
+	n.stop()
+	log.WithFields(log.Fields{"name": n.name, "id": n.id}).Info("Node removed from the cluster")
+	n.emitEvent("node_remove")
+}
+
+// Return the node at `addr`. The caller must hold the cluster lock.
 func (c *Cluster) getNode(addr string) *node {
 	for _, node := range c.nodes {
 		if node.addr == addr {
@@ -186,28 +359,10 @@ func (c *Cluster) Image(IdOrName string) *cluster.Image {
 	return nil
 }
 
-func (c *Cluster) Pull(name string, callback func(what, status string)) {
-	size := len(c.nodes)
-	done := make(chan bool, size)
-	for _, n := range c.nodes {
-		go func(nn *node) {
-			if callback != nil {
-				callback(nn.Name(), "")
-			}
-			nn.pull(name)
-			if callback != nil {
-				callback(nn.Name(), "downloaded")
-			}
-			done <- true
-		}(n)
-	}
-	for i := 0; i < size; i++ {
-		<-done
-	}
-}
-
 // Containers returns all the containers in the cluster.
 func (c *Cluster) Containers() []*cluster.Container {
+	ids := c.swarmIDs()
+
 	c.RLock()
 	defer c.RUnlock()
 
@@ -216,7 +371,7 @@ func (c *Cluster) Containers() []*cluster.Container {
 		out = append(out, n.Containers()...)
 	}
 
-	return out
+	return annotate(out, ids)
 }
 
 // Container returns the container with IdOrName in the cluster
@@ -226,19 +381,37 @@ func (c *Cluster) Container(IdOrName string) *cluster.Container {
 		return nil
 	}
 
+	// Exact cluster-wide IDs come first, then the names and engine IDs, and
+	// only then the cluster-wide ID prefixes: a container named `db` must not
+	// resolve to another one whose ID starts with "db".
+	ids := c.swarmIDs()
+	if engineID := c.engineByID(IdOrName, true); engineID != "" {
+		if container := c.containerOnNodes(engineID, ids); container != nil {
+			return container
+		}
+	}
+	if container := c.containerOnNodes(IdOrName, ids); container != nil {
+		return container
+	}
+	if engineID := c.engineByID(IdOrName, false); engineID != "" {
+		return c.containerOnNodes(engineID, ids)
+	}
+	return nil
+}
+
+func (c *Cluster) containerOnNodes(IdOrName string, ids map[string]string) *cluster.Container {
 	c.RLock()
 	defer c.RUnlock()
 	for _, n := range c.nodes {
 		if container := n.Container(IdOrName); container != nil {
-			return container
+			return withSwarmID(container, ids[container.Id])
 		}
 	}
-
 	return nil
 }
 
-// nodes returns all the nodess in the cluster.
-func (c *Cluster) listNodes() []cluster.Node {
+// Nodes returns all the nodes in the cluster.
+func (c *Cluster) Nodes() []cluster.Node {
 	c.RLock()
 	defer c.RUnlock()
 
@@ -250,14 +423,54 @@ func (c *Cluster) listNodes() []cluster.Node {
 	return out
 }
 
-func (c *Cluster) Info() [][2]string {
-	info := [][2]string{{"\bNodes", fmt.Sprintf("%d", len(c.nodes))}}
+// SelectNode returns the node the scheduler chooses for a container with `config`.
+func (c *Cluster) SelectNode(config *dockerclient.ContainerConfig) (cluster.Node, error) {
+	return c.selectNode(c.Nodes(), config)
+}
 
-	for _, node := range c.nodes {
-		info = append(info, [2]string{node.Name(), node.Addr()})
-		info = append(info, [2]string{" └ Containers", fmt.Sprintf("%d", len(node.Containers()))})
-		info = append(info, [2]string{" └ Reserved CPUs", fmt.Sprintf("%d / %d", node.UsedCpus(), node.TotalCpus())})
-		info = append(info, [2]string{" └ Reserved Memory", fmt.Sprintf("%s / %s", units.BytesSize(float64(node.UsedMemory())), units.BytesSize(float64(node.TotalMemory())))})
+// NodeStatuses returns the status of every node, sorted by name.
+func (c *Cluster) NodeStatuses() []*cluster.NodeStatus {
+	c.RLock()
+	defer c.RUnlock()
+
+	out := []*cluster.NodeStatus{}
+	for _, n := range c.nodes {
+		out = append(out, n.describe())
+	}
+	sort.Sort(byName(out))
+
+	return out
+}
+
+type byName []*cluster.NodeStatus
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+// Return the status of a node, as displayed by Info().
+func statusString(status *cluster.NodeStatus) string {
+	switch {
+	case !status.Healthy:
+		return "Unhealthy"
+	case status.Cordoned:
+		return "Cordoned"
+	case status.Warming:
+		return "Warming up"
+	}
+	return "Healthy"
+}
+
+func (c *Cluster) Info() [][2]string {
+	statuses := c.NodeStatuses()
+	info := [][2]string{{"\bNodes", fmt.Sprintf("%d", len(statuses))}}
+
+	for _, status := range statuses {
+		info = append(info, [2]string{status.Name, status.Addr})
+		info = append(info, [2]string{" └ Status", statusString(status)})
+		info = append(info, [2]string{" └ Containers", fmt.Sprintf("%d", status.Containers)})
+		info = append(info, [2]string{" └ Reserved CPUs", fmt.Sprintf("%d / %d", status.UsedCpus, status.TotalCpus)})
+		info = append(info, [2]string{" └ Reserved Memory", fmt.Sprintf("%s / %s", units.BytesSize(float64(status.UsedMemory)), units.BytesSize(float64(status.TotalMemory)))})
 	}
 
 	return info
@@ -1,11 +1,23 @@
 package swarm
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/discovery"
+	"github.com/docker/swarm/scheduler"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/strategy"
+	"github.com/docker/swarm/state"
 	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func createNode(t *testing.T, ID string, containers ...dockerclient.Container) *node {
@@ -21,8 +33,13 @@ func createNode(t *testing.T, ID string, containers ...dockerclient.Container) *
 }
 
 func TestContainerLookup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
 	c := &Cluster{
 		nodes: make(map[string]*node),
+		store: state.NewFileStore(dir),
 	}
 	container := dockerclient.Container{
 		Id:    "container-id",
@@ -45,4 +62,337 @@ func TestContainerLookup(t *testing.T) {
 	// Container node/name matching.
 	assert.NotNil(t, c.Container("test-node/container-name1"))
 	assert.NotNil(t, c.Container("test-node/container-name2"))
+
+	// Cluster-wide ID lookup.
+	assert.NoError(t, c.addState(&state.RequestedState{ID: "swarm-id", EngineID: "container-id"}))
+	container1 := c.Container("swarm-id")
+	assert.NotNil(t, container1)
+	assert.Equal(t, container1.Id, "container-id")
+	assert.Equal(t, container1.SwarmID, "swarm-id")
+	assert.Equal(t, container1.ClusterID(), "swarm-id")
+	// Cluster-wide ID prefix lookup.
+	assert.NotNil(t, c.Container("swarm-"))
+	// Engine ID lookups report the cluster-wide ID too.
+	assert.Equal(t, c.Container("container-id").ClusterID(), "swarm-id")
+	assert.Equal(t, c.Containers()[0].ClusterID(), "swarm-id")
+
+	// Names win over the cluster-wide ID prefixes.
+	other := dockerclient.Container{Id: "other-id", Names: []string{"/swarm"}}
+	n.addContainer(&cluster.Container{Container: other, Node: n})
+	assert.Equal(t, c.Container("swarm").Id, "other-id")
+	assert.Equal(t, c.Container("swarm-").Id, "container-id")
+
+	// Removing the container drops it from the store and the index.
+	client := mockclient.NewMockClient()
+	client.On("RemoveContainer", mock.Anything, true, true).Return(nil)
+	n.client = client
+	assert.NoError(t, c.RemoveContainer(c.Container("swarm-id"), true))
+	_, err = c.store.Get("swarm-id")
+	assert.Equal(t, err, state.ErrNotFound)
+	assert.Empty(t, c.swarmIDs())
+
+	// Containers created outside of swarm aren't in the store.
+	assert.NoError(t, c.RemoveContainer(c.Container("other-id"), true))
+}
+
+func TestContainerIDsReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ids-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var (
+		leader = false
+		store  = state.NewFileStore(dir)
+		c      = &Cluster{
+			nodes:   make(map[string]*node),
+			options: &cluster.Options{IsLeader: func() bool { return leader }},
+			store:   store,
+		}
+	)
+	n := createNode(t, "test-node", dockerclient.Container{Id: "id-1"}, dockerclient.Container{Id: "id-2"})
+	c.nodes[n.ID()] = n
+	assert.Equal(t, c.Container("id-1").ClusterID(), "id-1")
+
+	// Another manager sharing the store creates the containers: a miss reads
+	// the store again, once it wasn't read for a while.
+	assert.NoError(t, store.Add("swarm-id-1", &state.RequestedState{ID: "swarm-id-1", EngineID: "id-1"}))
+	c.idsLoaded = time.Now().Add(-idsReloadPeriod)
+	assert.Equal(t, c.Container("swarm-id-1").Id, "id-1")
+
+	assert.NoError(t, store.Add("swarm-id-2", &state.RequestedState{ID: "swarm-id-2", EngineID: "id-2"}))
+	assert.Nil(t, c.Container("swarm-id-2"))
+
+	// Taking the lead reads the store again.
+	leader = true
+	assert.Equal(t, c.Container("id-2").ClusterID(), "swarm-id-2")
+
+	// The containers of the nodes are left alone, the lookups return copies.
+	for _, container := range c.Containers() {
+		assert.NotEqual(t, container.SwarmID, "")
+	}
+	for _, container := range n.Containers() {
+		assert.Equal(t, container.SwarmID, "")
+	}
+}
+
+type FakeEventHandler struct {
+	events []*cluster.Event
+
+	sync.Mutex
+}
+
+func (h *FakeEventHandler) Handle(e *cluster.Event) error {
+	h.Lock()
+	defer h.Unlock()
+
+	h.events = append(h.events, e)
+	return nil
+}
+
+// Return the events handled so far.
+func (h *FakeEventHandler) Events() []*cluster.Event {
+	h.Lock()
+	defer h.Unlock()
+
+	return append([]*cluster.Event{}, h.events...)
+}
+
+func TestReconcile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reconcile-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := state.NewFileStore(dir)
+	assert.NoError(t, store.Initialize())
+
+	var (
+		handler = &FakeEventHandler{}
+		config  = &dockerclient.ContainerConfig{Image: "busybox", CpuShares: 1}
+		c       = &Cluster{
+			eventHandler: handler,
+			nodes:        make(map[string]*node),
+			scheduler:    scheduler.New(&strategy.RandomPlacementStrategy{}, []filter.Filter{&filter.HealthFilter{}}),
+			options:      &cluster.Options{RescheduleGrace: 30},
+			store:        store,
+			pending:      make(map[string]time.Time),
+			stale:        make(map[string]string),
+		}
+	)
+
+	// A dead node still holding the container.
+	dead := createNode(t, "dead-node", dockerclient.Container{Id: "old-id", Names: []string{"/name"}, Status: "Up 2 hours"})
+	dead.healthy = false
+	c.nodes[dead.ID()] = dead
+	assert.NoError(t, store.Add("swarm-id", &state.RequestedState{ID: "swarm-id", EngineID: "old-id", Name: "name", Config: config}))
+
+	// A healthy node to reschedule the container onto.
+	client := mockclient.NewMockClient()
+	alive := createNode(t, "alive-node")
+	alive.client = client
+	alive.Cpus = 1
+	c.nodes[alive.ID()] = alive
+
+	// The container is only flagged as pending during the grace period.
+	now := time.Now()
+	c.reconcile(now)
+	_, pending := c.pending["swarm-id"]
+	assert.True(t, pending)
+	c.reconcile(now.Add(10 * time.Second))
+	assert.Len(t, handler.Events(), 0)
+
+	client.On("CreateContainer", mock.Anything, "name").Return("new-id", nil).Once()
+	client.On("ListContainers", true, false, fmt.Sprintf("{%q:[%q]}", "id", "new-id")).Return([]dockerclient.Container{{Id: "new-id"}}, nil)
+	client.On("InspectContainer", "new-id").Return(&dockerclient.ContainerInfo{Config: config}, nil)
+	client.On("StartContainer", "new-id", &config.HostConfig).Return(nil).Once()
+
+	// Past the grace period, the container is re-created on the healthy node.
+	c.reconcile(now.Add(30 * time.Second))
+	_, pending = c.pending["swarm-id"]
+	assert.False(t, pending)
+	assert.NotNil(t, alive.Container("new-id"))
+	assert.Nil(t, dead.Container("old-id"))
+
+	// The container keeps its cluster-wide ID.
+	st, err := store.Get("swarm-id")
+	assert.NoError(t, err)
+	assert.Equal(t, st.Name, "name")
+	assert.Equal(t, st.EngineID, "new-id")
+	assert.Equal(t, c.Container("swarm-id").Id, "new-id")
+
+	assert.Len(t, handler.Events(), 1)
+	assert.Equal(t, handler.Events()[0].Status, "container_rescheduled")
+	assert.Equal(t, handler.Events()[0].Id, "swarm-id")
+
+	// Running containers are left alone.
+	c.reconcile(now.Add(time.Minute))
+	assert.Equal(t, len(c.pending), 0)
+
+	// The old container is removed once its node comes back.
+	deadClient := mockclient.NewMockClient()
+	deadClient.On("RemoveContainer", "old-id", true, true).Return(nil).Once()
+	dead.client = deadClient
+	dead.healthy = true
+	dead.addContainer(&cluster.Container{Container: dockerclient.Container{Id: "old-id", Names: []string{"/name"}}, Node: dead})
+	c.reconcile(now.Add(2 * time.Minute))
+	assert.Nil(t, dead.Container("old-id"))
+	assert.Equal(t, len(c.stale), 0)
+	assert.Equal(t, c.Container("swarm-id").Id, "new-id")
+
+	client.Mock.AssertExpectations(t)
+	deadClient.Mock.AssertExpectations(t)
+}
+
+func TestSchedule(t *testing.T) {
+	c := &Cluster{
+		nodes:     make(map[string]*node),
+		scheduler: scheduler.New(&strategy.SpreadPlacementStrategy{}, []filter.Filter{&filter.HealthFilter{}, &filter.ConstraintFilter{}}),
+	}
+	for i, zone := range []string{"a", "a", "b"} {
+		n := createNode(t, fmt.Sprintf("node-%d", i))
+		n.Cpus = 2
+		n.labels = map[string]string{"zone": zone}
+		c.nodes[n.id] = n
+	}
+	c.nodes["node-0"].healthy = false
+
+	decision := c.Schedule(&dockerclient.ContainerConfig{Env: []string{"constraint:zone==a"}})
+	assert.Len(t, decision.Candidates, 3)
+	assert.Equal(t, decision.Filters[0].Name, "health")
+	assert.Equal(t, decision.Filters[0].Rejected["node-0"], filter.ErrNoHealthyNodeAvailable.Error())
+	assert.Equal(t, decision.Filters[1].Name, "constraint")
+	assert.Equal(t, decision.Filters[1].Rejected["node-2"], "unable to find a node that satisfies zone==a")
+	assert.Equal(t, decision.Filters[1].Accepted, []string{"node-1"})
+	assert.Equal(t, decision.Strategy, "spread")
+	assert.Equal(t, decision.Scores["node-1"], 0)
+	assert.Equal(t, decision.Node, "node-1")
+	assert.Empty(t, decision.Error)
+
+	// Failures are explained too.
+	decision = c.Schedule(&dockerclient.ContainerConfig{Env: []string{"constraint:zone==c"}})
+	assert.Len(t, decision.Filters, 2)
+	assert.Len(t, decision.Filters[1].Rejected, 2)
+	assert.Empty(t, decision.Node)
+	assert.Equal(t, decision.Error, "unable to find a node that satisfies zone==c")
+
+	decision = c.Schedule(&dockerclient.ContainerConfig{CpuShares: 4})
+	assert.Len(t, decision.Filters[1].Accepted, 2)
+	assert.Equal(t, decision.Error, strategy.ErrNoResourcesAvailable.Error())
+}
+
+// A discovery service listing a fixed set of entries, which changes on demand.
+type fakeDiscovery struct {
+	entries  []*discovery.Entry
+	callback discovery.WatchCallback
+}
+
+func (d *fakeDiscovery) Initialize(string, int) error           { return nil }
+func (d *fakeDiscovery) Fetch() ([]*discovery.Entry, error)     { return d.entries, nil }
+func (d *fakeDiscovery) Watch(callback discovery.WatchCallback) { d.callback = callback }
+func (d *fakeDiscovery) Register(string) error                  { return nil }
+
+func (d *fakeDiscovery) set(addrs ...string) {
+	entries, _ := discovery.CreateEntries(addrs)
+	d.entries = entries
+	d.callback(entries)
+}
+
+func TestNodeRemoval(t *testing.T) {
+	var (
+		handler = &FakeEventHandler{}
+		c       = &Cluster{
+			eventHandler: handler,
+			nodes:        make(map[string]*node),
+			options:      &cluster.Options{RemoveGrace: 60},
+			removals:     make(map[string]*time.Timer),
+		}
+		d = &fakeDiscovery{}
+	)
+	d.Watch(c.newEntries)
+
+	client := mockclient.NewMockClient()
+	client.On("StopAllMonitorEvents").Return().Once()
+	for _, addr := range []string{"1.1.1.1:2375", "2.2.2.2:2375"} {
+		n := createNode(t, addr)
+		n.eventHandler = c
+		c.nodes[n.id] = n
+	}
+	gone := c.nodes["2.2.2.2:2375"]
+	gone.client = client
+
+	// The node is kept during the grace period, and for good if it's back.
+	d.set("1.1.1.1:2375")
+	assert.Len(t, c.Nodes(), 2)
+	assert.NotNil(t, c.removals["2.2.2.2:2375"])
+	d.set("1.1.1.1:2375", "2.2.2.2:2375")
+	assert.Nil(t, c.removals["2.2.2.2:2375"])
+	assert.Len(t, c.Nodes(), 2)
+
+	// Past the grace period, the node is removed and stopped.
+	c.options.RemoveGrace = 0
+	d.set("1.1.1.1:2375")
+	timeout := time.After(5 * time.Second)
+	for len(c.Nodes()) != 1 {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("the node was never removed")
+		}
+	}
+	assert.Equal(t, c.Nodes()[0].Addr(), "1.1.1.1:2375")
+	assert.Len(t, c.removals, 0)
+
+	events := handler.Events()
+	for len(events) == 0 {
+		time.Sleep(10 * time.Millisecond)
+		events = handler.Events()
+	}
+	assert.Equal(t, events[0].Status, "node_remove")
+	assert.Equal(t, events[0].Node.Addr(), "2.2.2.2:2375")
+
+	// The node no longer refreshes.
+	select {
+	case <-gone.stopCh:
+	default:
+		t.Fatal("the node wasn't stopped")
+	}
+	client.Mock.AssertExpectations(t)
+}
+
+// Return whether a connection retry is pending for the node at `addr`.
+func (c *Cluster) retrying(addr string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	_, exists := c.retries[addr]
+	return exists
+}
+
+func TestNodeRetry(t *testing.T) {
+	var (
+		c = &Cluster{
+			nodes:    make(map[string]*node),
+			options:  &cluster.Options{Heartbeat: 1},
+			removals: make(map[string]*time.Timer),
+			retries:  make(map[string]*time.Timer),
+		}
+		d = &fakeDiscovery{}
+	)
+	d.Watch(c.newEntries)
+
+	// Nothing listens there: the node is retried every heartbeat.
+	d.set("127.0.0.1:1")
+	timeout := time.After(5 * time.Second)
+	for !c.retrying("127.0.0.1:1") {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("the node was never retried")
+		}
+	}
+	assert.Len(t, c.Nodes(), 0)
+
+	// Until it leaves the discovery service.
+	d.set()
+	time.Sleep(1500 * time.Millisecond)
+	assert.False(t, c.retrying("127.0.0.1:1"))
+	assert.Len(t, c.Nodes(), 0)
 }
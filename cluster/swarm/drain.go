@@ -0,0 +1,100 @@
+package swarm
+
+import (
+	"errors"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/state"
+)
+
+var errUnmanaged = errors.New("not created through swarm")
+
+// Return the node whose ID or name is `IdOrName`.
+func (c *Cluster) lookupNode(IdOrName string) *node {
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, n := range c.nodes {
+		if n.id == IdOrName || n.name == IdOrName {
+			return n
+		}
+	}
+	return nil
+}
+
+// Keep a node out of scheduling, or bring it back.
+func (c *Cluster) Cordon(IdOrName string, cordoned bool) error {
+	n := c.lookupNode(IdOrName)
+	if n == nil {
+		return cluster.ErrNodeNotFound
+	}
+
+	if n.setCordoned(cordoned) {
+		log.WithFields(log.Fields{"name": n.name, "cordoned": cordoned}).Info("Node cordon changed")
+	}
+	return nil
+}
+
+// Cordon a node and move its containers onto the nodes chosen by the scheduler.
+func (c *Cluster) Drain(IdOrName string) ([]*cluster.Move, error) {
+	n := c.lookupNode(IdOrName)
+	if n == nil {
+		return nil, cluster.ErrNodeNotFound
+	}
+	n.setCordoned(true)
+	n.emitEvent("node_drain")
+	log.WithField("name", n.name).Info("Draining node")
+
+	states := make(map[string]*state.RequestedState)
+	for _, st := range c.store.All() {
+		states[st.Engine()] = st
+	}
+
+	// Never move a container back onto the drained node, whether or not the
+	// cordon filter is enabled.
+	candidates := []cluster.Node{}
+	for _, other := range c.healthyNodes() {
+		if other != cluster.Node(n) {
+			candidates = append(candidates, other)
+		}
+	}
+
+	moves := []*cluster.Move{}
+	for _, container := range n.Containers() {
+		move := &cluster.Move{ID: container.Id, From: n.name}
+		if len(container.Names) > 0 {
+			move.Name = strings.TrimPrefix(container.Names[0], "/")
+		}
+		moves = append(moves, move)
+
+		st, exists := states[container.Id]
+		if !exists {
+			move.Error = errUnmanaged.Error()
+			continue
+		}
+		move.ID = st.ID
+		if st.Mode == state.ModeGlobal {
+			move.Error = ErrGlobalMove.Error()
+			continue
+		}
+
+		to, err := c.selectNode(candidates, st.Config)
+		if err != nil {
+			move.Error = err.Error()
+			continue
+		}
+		nn, ok := to.(*node)
+		if !ok {
+			continue
+		}
+		move.To = nn.name
+
+		if err := c.migrate(&migration{state: st, container: container, from: n, to: nn}, "container_drained"); err != nil {
+			log.WithFields(log.Fields{"id": st.ID, "from": n.name, "to": nn.name}).Errorf("Unable to move container: %v", err)
+			move.Error = err.Error()
+		}
+	}
+	return moves, nil
+}
@@ -0,0 +1,119 @@
+package swarm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/strategy"
+	"github.com/docker/swarm/state"
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCordon(t *testing.T) {
+	// Without the cordon filter binpacking would prefer node-1: the drained
+	// node must be left out of the candidates anyway.
+	var (
+		handler = &FakeEventHandler{}
+		c       = &Cluster{nodes: make(map[string]*node)}
+		n       = createNode(t, "node-1")
+	)
+	n.eventHandler = handler
+	c.nodes[n.ID()] = n
+
+	assert.Equal(t, c.Cordon("unknown", true), cluster.ErrNodeNotFound)
+
+	assert.NoError(t, c.Cordon("node-1", true))
+	assert.True(t, n.IsCordoned())
+	assert.Equal(t, statusString(n.describe()), "Cordoned")
+
+	// Cordoning twice is a no-op.
+	assert.NoError(t, c.Cordon("node-1", true))
+	assert.Len(t, handler.Events(), 1)
+	assert.Equal(t, handler.Events()[0].Status, "node_cordon")
+
+	assert.NoError(t, c.Cordon("node-1", false))
+	assert.False(t, n.IsCordoned())
+	assert.Len(t, handler.Events(), 2)
+	assert.Equal(t, handler.Events()[1].Status, "node_uncordon")
+}
+
+func TestDrain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drain-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := state.NewFileStore(dir)
+	assert.NoError(t, store.Initialize())
+
+	var (
+		handler = &FakeEventHandler{}
+		c       = &Cluster{
+			eventHandler: handler,
+			nodes:        make(map[string]*node),
+			scheduler:    scheduler.New(&strategy.BinPackingPlacementStrategy{}, []filter.Filter{&filter.HealthFilter{}}),
+			options:      &cluster.Options{},
+			store:        store,
+		}
+	)
+
+	old := mockclient.NewMockClient()
+	old.On("RemoveContainer", "managed", true, true).Return(nil).Once()
+	node1 := createNode(t, "node-1", dockerclient.Container{Id: "unmanaged", Names: []string{"/unmanaged"}})
+	node1.client = old
+	node1.eventHandler = handler
+	node1.Cpus = 2
+	c.nodes[node1.ID()] = node1
+	addReservedContainer(t, store, node1, "managed", 1)
+
+	client := mockclient.NewMockClient()
+	client.On("CreateContainer", mock.Anything, "managed").Return("new-managed", nil).Once()
+	client.On("ListContainers", true, false, fmt.Sprintf("{%q:[%q]}", "id", "new-managed")).Return([]dockerclient.Container{{Id: "new-managed", Names: []string{"/managed"}}}, nil)
+	client.On("InspectContainer", "new-managed").Return(&dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{}}, nil)
+	client.On("StartContainer", "new-managed", mock.Anything).Return(nil).Once()
+	node2 := createNode(t, "node-2")
+	node2.client = client
+	node2.Cpus = 1
+	c.nodes[node2.ID()] = node2
+
+	_, err = c.Drain("unknown")
+	assert.Equal(t, err, cluster.ErrNodeNotFound)
+
+	moves, err := c.Drain("node-1")
+	assert.NoError(t, err)
+	assert.True(t, node1.IsCordoned())
+	assert.Len(t, moves, 2)
+
+	for _, move := range moves {
+		switch move.Name {
+		case "managed":
+			assert.Equal(t, move.ID, "swarm-managed")
+			assert.Equal(t, move.To, "node-2")
+			assert.Equal(t, move.Error, "")
+		case "unmanaged":
+			// Containers without requested state stay where they are.
+			assert.Equal(t, move.To, "")
+			assert.Equal(t, move.Error, errUnmanaged.Error())
+		default:
+			t.Errorf("unexpected move of %s", move.Name)
+		}
+	}
+	assert.Nil(t, node1.Container("managed"))
+	assert.NotNil(t, node2.Container("new-managed"))
+
+	statuses := []string{}
+	for _, e := range handler.Events() {
+		statuses = append(statuses, e.Status)
+	}
+	assert.Equal(t, statuses, []string{"node_cordon", "node_drain", "container_drained"})
+
+	client.Mock.AssertExpectations(t)
+	old.Mock.AssertExpectations(t)
+}
@@ -0,0 +1,114 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/state"
+	"github.com/samalba/dockerclient"
+)
+
+var (
+	ErrGlobalName = errors.New("global scheduling requires a container name")
+	ErrGlobalMove = errors.New("global containers cannot move")
+)
+
+// Return the scheduling mode requested through the `scheduling:<mode>` env hint.
+func schedulingMode(config *dockerclient.ContainerConfig) string {
+	for _, env := range config.Env {
+		if env == "scheduling:"+state.ModeGlobal {
+			return state.ModeGlobal
+		}
+	}
+	return ""
+}
+
+// Create a copy of the container on every node accepted by the filters. The
+// first copy is returned to the caller, the other ones are started right away.
+func (c *Cluster) createGlobalContainer(config *dockerclient.ContainerConfig, name string) (*cluster.Container, *cluster.Decision, error) {
+	if name == "" {
+		return nil, nil, ErrGlobalName
+	}
+
+	nodes, decision, err := c.scheduler.Filter(c.Nodes(), config)
+	if err != nil {
+		return nil, decision, err
+	}
+
+	var first *cluster.Container
+	for _, n := range nodes {
+		nn, ok := n.(*node)
+		if !ok {
+			continue
+		}
+
+		container, err := c.createGlobalCopy(nn, config, name, first != nil)
+		if err != nil {
+			log.WithFields(log.Fields{"name": nn.name, "container": name}).Errorf("Unable to create global container: %v", err)
+			continue
+		}
+		if first == nil {
+			first = container
+		}
+	}
+
+	if first == nil {
+		return nil, decision, fmt.Errorf("unable to create global container %s on any node", name)
+	}
+	return first, decision, nil
+}
+
+// Create, and optionally start, one copy of a global container on `n`.
+func (c *Cluster) createGlobalCopy(n *node, config *dockerclient.ContainerConfig, name string, start bool) (*cluster.Container, error) {
+	swarmID, err := newSwarmID()
+	if err != nil {
+		return nil, err
+	}
+
+	container, err := n.create(config, name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if start {
+		if err := n.start(container, &config.HostConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	st := &state.RequestedState{
+		ID:       swarmID,
+		EngineID: container.Id,
+		Name:     name,
+		Config:   config,
+		Mode:     state.ModeGlobal,
+	}
+	return withSwarmID(container, swarmID), c.addState(st)
+}
+
+// Start the copies of the global containers missing from a newly added node.
+func (c *Cluster) startGlobalContainers(n *node) {
+	seen := make(map[string]bool)
+
+	for _, st := range c.store.All() {
+		if st.Mode != state.ModeGlobal || seen[st.Name] {
+			continue
+		}
+		seen[st.Name] = true
+
+		if n.Container(st.Name) != nil {
+			continue
+		}
+		if accepted, err := c.scheduler.FilterNodes([]cluster.Node{n}, st.Config); err != nil || len(accepted) == 0 {
+			continue
+		}
+
+		if _, err := c.createGlobalCopy(n, st.Config, st.Name, true); err != nil {
+			log.WithFields(log.Fields{"name": n.name, "container": st.Name}).Errorf("Unable to start global container: %v", err)
+			continue
+		}
+		log.WithFields(log.Fields{"name": n.name, "container": st.Name}).Info("Started global container on new node")
+	}
+}
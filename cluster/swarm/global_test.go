@@ -0,0 +1,98 @@
+package swarm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/strategy"
+	"github.com/docker/swarm/state"
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Create a node whose client will create the container `name` as `id`.
+func createGlobalNode(t *testing.T, ID, name, id string) *node {
+	client := mockclient.NewMockClient()
+	client.On("CreateContainer", mock.Anything, name).Return(id, nil)
+	client.On("ListContainers", true, false, fmt.Sprintf("{%q:[%q]}", "id", id)).Return([]dockerclient.Container{{Id: id, Names: []string{"/" + name}}}, nil)
+	client.On("InspectContainer", id).Return(&dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{}}, nil)
+	client.On("StartContainer", id, mock.Anything).Return(nil)
+
+	n := createNode(t, ID)
+	n.client = client
+	n.Cpus = 1
+	return n
+}
+
+func TestGlobalContainer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "global-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := state.NewFileStore(dir)
+	assert.NoError(t, store.Initialize())
+
+	c := &Cluster{
+		nodes:     make(map[string]*node),
+		scheduler: scheduler.New(&strategy.RandomPlacementStrategy{}, []filter.Filter{&filter.ConstraintFilter{}}),
+		options:   &cluster.Options{},
+		store:     store,
+	}
+
+	node1 := createGlobalNode(t, "node-1", "logger", "id-1")
+	node1.labels["zone"] = "a"
+	c.nodes[node1.ID()] = node1
+	node2 := createGlobalNode(t, "node-2", "logger", "id-2")
+	node2.labels["zone"] = "b"
+	c.nodes[node2.ID()] = node2
+	node3 := createGlobalNode(t, "node-3", "logger", "id-3")
+	node3.labels["zone"] = "c"
+	c.nodes[node3.ID()] = node3
+
+	config := &dockerclient.ContainerConfig{Image: "logger", Env: []string{"scheduling:global", "constraint:zone!=c"}}
+
+	// Global containers must be named.
+	_, _, err = c.CreateContainer(config, "")
+	assert.Equal(t, err, ErrGlobalName)
+
+	// A copy is created on every node accepted by the filters.
+	container, decision, err := c.CreateContainer(config, "logger")
+	assert.NoError(t, err)
+	assert.NotNil(t, container)
+	decision.Explain()
+	assert.Equal(t, decision.Filters[0].Rejected["node-3"], "unable to find a node that satisfies zone!=c")
+	assert.NotNil(t, node1.Container("logger"))
+	assert.NotNil(t, node2.Container("logger"))
+	assert.Nil(t, node3.Container("logger"))
+
+	states := store.All()
+	assert.Len(t, states, 2)
+	for _, st := range states {
+		assert.Equal(t, st.Mode, state.ModeGlobal)
+		assert.Equal(t, st.Name, "logger")
+	}
+
+	// A new node accepted by the filters gets its own copy.
+	node4 := createGlobalNode(t, "node-4", "logger", "id-4")
+	node4.labels["zone"] = "d"
+	c.nodes[node4.ID()] = node4
+	c.startGlobalContainers(node4)
+	assert.NotNil(t, node4.Container("logger"))
+	assert.Len(t, store.All(), 3)
+
+	// But only once.
+	c.startGlobalContainers(node4)
+	assert.Len(t, store.All(), 3)
+
+	// Nodes rejected by the filters are left alone.
+	c.startGlobalContainers(node3)
+	assert.Nil(t, node3.Container("logger"))
+	assert.Len(t, store.All(), 3)
+}
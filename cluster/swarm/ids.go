@@ -0,0 +1,171 @@
+package swarm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/state"
+)
+
+// Mint a cluster-wide container ID, formatted like the engine ones.
+func newSwarmID() (string, error) {
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Reload the index of the cluster-wide IDs at most this often when a lookup
+// misses.
+const idsReloadPeriod = time.Second
+
+// Rebuild the index of the cluster-wide IDs from `states`.
+func (c *Cluster) loadIDs(states []*state.RequestedState) {
+	ids := make(map[string]string)
+	for _, st := range states {
+		ids[st.Engine()] = st.ID
+	}
+	leader := c.isLeader()
+
+	c.idsLock.Lock()
+	c.ids = ids
+	c.idsLoaded = time.Now()
+	c.idsLeader = leader
+	c.idsLock.Unlock()
+}
+
+// Return the cluster-wide IDs, keyed by engine ID. The index is read from the
+// store the first time and whenever this manager took or lost the lead, it is
+// otherwise changed through addState, replaceState and removeState.
+func (c *Cluster) swarmIDs() map[string]string {
+	leader := c.isLeader()
+	c.idsLock.Lock()
+	stale := c.ids == nil || c.idsLeader != leader
+	c.idsLock.Unlock()
+	if stale {
+		c.loadIDs(c.store.All())
+	}
+
+	c.idsLock.Lock()
+	defer c.idsLock.Unlock()
+	ids := make(map[string]string, len(c.ids))
+	for engineID, id := range c.ids {
+		ids[engineID] = id
+	}
+	return ids
+}
+
+// Read the index from the store again after a lookup missed, as the other
+// managers sharing the store may have changed it. Return false if it was
+// read too recently to try again.
+func (c *Cluster) reloadIDs() bool {
+	c.idsLock.Lock()
+	recent := c.ids != nil && time.Since(c.idsLoaded) < idsReloadPeriod
+	c.idsLock.Unlock()
+	if recent {
+		return false
+	}
+	c.loadIDs(c.store.All())
+	return true
+}
+
+func (c *Cluster) indexState(st *state.RequestedState) {
+	c.swarmIDs()
+
+	c.idsLock.Lock()
+	defer c.idsLock.Unlock()
+	for engineID, id := range c.ids {
+		if id == st.ID {
+			delete(c.ids, engineID)
+		}
+	}
+	c.ids[st.Engine()] = st.ID
+}
+
+func (c *Cluster) addState(st *state.RequestedState) error {
+	if err := c.store.Add(st.ID, st); err != nil {
+		return err
+	}
+	c.indexState(st)
+	return nil
+}
+
+func (c *Cluster) replaceState(st *state.RequestedState) error {
+	if err := c.store.Replace(st.ID, st); err != nil {
+		return err
+	}
+	c.indexState(st)
+	return nil
+}
+
+func (c *Cluster) removeState(id string) error {
+	err := c.store.Remove(id)
+	if err != nil && err != state.ErrNotFound {
+		return err
+	}
+
+	c.swarmIDs()
+	c.idsLock.Lock()
+	for engineID, swarmID := range c.ids {
+		if swarmID == id {
+			delete(c.ids, engineID)
+		}
+	}
+	c.idsLock.Unlock()
+	return err
+}
+
+// Return the engine ID of the container whose cluster-wide ID is `id`, or
+// with `exact` false, whose cluster-wide ID starts with the unique prefix `id`.
+func (c *Cluster) engineByID(id string, exact bool) string {
+	if engineID := c.findEngineID(id, exact); engineID != "" || !c.reloadIDs() {
+		return engineID
+	}
+	return c.findEngineID(id, exact)
+}
+
+func (c *Cluster) findEngineID(id string, exact bool) string {
+	found := ""
+	for engineID, swarmID := range c.swarmIDs() {
+		if swarmID == id {
+			return engineID
+		}
+		if !exact && strings.HasPrefix(swarmID, id) {
+			if found != "" {
+				// Ambiguous prefix.
+				return ""
+			}
+			found = engineID
+		}
+	}
+	if exact {
+		return ""
+	}
+	return found
+}
+
+// Return copies of the containers with their cluster-wide ID filled in.
+func annotate(containers []*cluster.Container, ids map[string]string) []*cluster.Container {
+	out := make([]*cluster.Container, 0, len(containers))
+	for _, container := range containers {
+		out = append(out, withSwarmID(container, ids[container.Id]))
+	}
+	return out
+}
+
+// Return a copy of `container` with the cluster-wide ID `id`. The containers
+// of the nodes are shared by every request and refresh, they are only written
+// to under the lock of their node.
+func withSwarmID(container *cluster.Container, id string) *cluster.Container {
+	if n, ok := container.Node.(*node); ok {
+		n.RLock()
+		defer n.RUnlock()
+	}
+	copied := *container
+	copied.SwarmID = id
+	return &copied
+}
@@ -0,0 +1,258 @@
+package swarm
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/samalba/dockerclient"
+)
+
+// Send a request the engine client doesn't cover to the node. Return the body
+// of the response if the engine accepted the request, its error otherwise.
+func (n *node) request(method, path string, query url.Values, header http.Header, body io.Reader) (io.ReadCloser, error) {
+	var (
+		transport = &http.Transport{DisableKeepAlives: true}
+		scheme    = "http"
+	)
+	if n.tlsConfig != nil {
+		transport.TLSClientConfig = n.tlsConfig
+		scheme = "https"
+	}
+
+	uri := scheme + "://" + n.addr + path
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, uri, body)
+	if err != nil {
+		if closer, ok := body.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		data, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s: %s", http.StatusText(resp.StatusCode), strings.TrimSpace(string(data)))
+	}
+	return resp.Body, nil
+}
+
+// Same as request, reading the whole response.
+func (n *node) requestAll(method, path string, query url.Values, body io.Reader) ([]byte, error) {
+	resp, err := n.request(method, path, query, nil, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	return ioutil.ReadAll(resp)
+}
+
+// Return the healthy nodes holding the image `IdOrName`.
+func (c *Cluster) imageHolders(IdOrName string) []*node {
+	c.RLock()
+	defer c.RUnlock()
+
+	nodes := []*node{}
+	for _, n := range c.nodes {
+		if n.IsHealthy() && n.Image(IdOrName) != nil {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// Run `action` on every node concurrently, and refresh the images of the
+// nodes where it succeeded. Return the result of every node.
+func imageFanOut(nodes []*node, what string, action func(*node) ([]byte, error)) []*cluster.ImageResult {
+	var (
+		wg      sync.WaitGroup
+		results = make([]*cluster.ImageResult, len(nodes))
+	)
+
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+
+			results[i] = &cluster.ImageResult{Node: n}
+			body, err := action(n)
+			if err != nil {
+				log.WithField("name", n.name).Errorf("Unable to %s: %v", what, err)
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Body = body
+			if err := n.refreshImages(); err != nil {
+				log.WithField("name", n.name).Errorf("Unable to refresh the images: %v", err)
+			}
+		}(i, n)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Tag the image `IdOrName` on every healthy node holding it.
+func (c *Cluster) TagImage(IdOrName, repo, tag string, force bool) ([]*cluster.ImageResult, error) {
+	nodes := c.imageHolders(IdOrName)
+	if len(nodes) == 0 {
+		return nil, cluster.ErrImageNotFound
+	}
+
+	query := url.Values{}
+	query.Set("repo", repo)
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	if force {
+		query.Set("force", "1")
+	}
+	return imageFanOut(nodes, "tag "+IdOrName, func(n *node) ([]byte, error) {
+		return n.requestAll("POST", "/images/"+IdOrName+"/tag", query, nil)
+	}), nil
+}
+
+// Remove the image `IdOrName` from every healthy node holding it.
+func (c *Cluster) RemoveImage(IdOrName string, force, noprune bool) ([]*cluster.ImageResult, error) {
+	nodes := c.imageHolders(IdOrName)
+	if len(nodes) == 0 {
+		return nil, cluster.ErrImageNotFound
+	}
+
+	query := url.Values{}
+	if force {
+		query.Set("force", "1")
+	}
+	if noprune {
+		query.Set("noprune", "1")
+	}
+	return imageFanOut(nodes, "remove "+IdOrName, func(n *node) ([]byte, error) {
+		return n.requestAll("DELETE", "/images/"+IdOrName, query, nil)
+	}), nil
+}
+
+// Ignore the writes once one failed, so a node failing doesn't stop the
+// others from receiving their copy.
+type discardOnError struct {
+	w      io.Writer
+	failed bool
+}
+
+func (d *discardOnError) Write(p []byte) (int, error) {
+	if !d.failed {
+		if _, err := d.w.Write(p); err != nil {
+			d.failed = true
+		}
+	}
+	return len(p), nil
+}
+
+// Load the image archive on every healthy node, or on the ones matching
+// `constraints`.
+func (c *Cluster) LoadImage(archive io.Reader, constraints []string) ([]*cluster.ImageResult, error) {
+	config := &dockerclient.ContainerConfig{}
+	for _, constraint := range constraints {
+		config.Env = append(config.Env, "constraint:"+constraint)
+	}
+	candidates, err := filter.ApplyFilters([]filter.Filter{&filter.HealthFilter{}, &filter.ConstraintFilter{}}, config, c.Nodes())
+	if err != nil {
+		return nil, err
+	}
+
+	// The archive is only read once and copied to every node. The engine
+	// request closes the pipe of a node once done, failed or not.
+	var (
+		nodes   = []*node{}
+		readers = make(map[*node]*io.PipeReader)
+		pipes   = []*io.PipeWriter{}
+		writers = []io.Writer{}
+	)
+	for _, candidate := range candidates {
+		n, ok := candidate.(*node)
+		if !ok {
+			continue
+		}
+		pr, pw := io.Pipe()
+		nodes = append(nodes, n)
+		readers[n] = pr
+		pipes = append(pipes, pw)
+		writers = append(writers, &discardOnError{w: pw})
+	}
+	if len(nodes) == 0 {
+		return nil, cluster.ErrNodeNotFound
+	}
+	go func() {
+		// A failed read of the archive fails the load on every node,
+		// rather than loading a truncated archive.
+		_, err := io.Copy(io.MultiWriter(writers...), archive)
+		for _, pw := range pipes {
+			pw.CloseWithError(err)
+		}
+	}()
+
+	return imageFanOut(nodes, "load images", func(n *node) ([]byte, error) {
+		return n.requestAll("POST", "/images/load", nil, readers[n])
+	}), nil
+}
+
+// Push the image `IdOrName` from a healthy node holding it.
+func (c *Cluster) PushImage(IdOrName, tag, authConfig string) (io.ReadCloser, error) {
+	nodes := c.imageHolders(IdOrName)
+	if len(nodes) == 0 {
+		return nil, cluster.ErrImageNotFound
+	}
+
+	query := url.Values{}
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	header := http.Header{}
+	if authConfig != "" {
+		header.Set("X-Registry-Auth", authConfig)
+	}
+	return nodes[0].request("POST", "/images/"+IdOrName+"/push", query, header, nil)
+}
+
+// Save the images `names` from a single healthy node holding all of them.
+func (c *Cluster) SaveImages(names []string) (io.ReadCloser, error) {
+	c.RLock()
+	var holder *node
+	for _, n := range c.nodes {
+		if !n.IsHealthy() {
+			continue
+		}
+		holder = n
+		for _, name := range names {
+			if n.Image(name) == nil {
+				holder = nil
+				break
+			}
+		}
+		if holder != nil {
+			break
+		}
+	}
+	c.RUnlock()
+
+	if holder == nil {
+		return nil, cluster.ErrImageNotFound
+	}
+	return holder.request("GET", "/images/get", url.Values{"names": names}, nil, nil)
+}
@@ -0,0 +1,156 @@
+package swarm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// Give the node the images tagged `tags`.
+func setImages(n *node, tags ...string) {
+	n.images = nil
+	for _, tag := range tags {
+		n.images = append(n.images, &cluster.Image{Image: dockerclient.Image{Id: tag + "-id", RepoTags: []string{tag}}, Node: n})
+	}
+}
+
+// Return the result of the node `name`.
+func resultOf(results []*cluster.ImageResult, name string) *cluster.ImageResult {
+	for _, result := range results {
+		if result.Node.Name() == name {
+			return result
+		}
+	}
+	return nil
+}
+
+// An archive failing half-way.
+type failingReader struct {
+	read bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.read {
+		return 0, errors.New("connection reset")
+	}
+	r.read = true
+	return copy(p, "arch"), nil
+}
+
+func TestImages(t *testing.T) {
+	var (
+		lock     sync.Mutex
+		requests = make(map[string][]string)
+		c        = &Cluster{nodes: make(map[string]*node)}
+	)
+	record := func(name string, r *http.Request) string {
+		body, _ := ioutil.ReadAll(r.Body)
+		lock.Lock()
+		defer lock.Unlock()
+		request := r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery + " " + string(body)
+		requests[name] = append(requests[name], request)
+		return request
+	}
+
+	node1, server1 := createPullNode(t, "node-1", func(w http.ResponseWriter, r *http.Request) {
+		record("node-1", r)
+		switch {
+		case r.Method == "DELETE":
+			fmt.Fprint(w, `[{"Untagged":"foo:latest"},{"Deleted":"foo-id"}]`)
+		case strings.HasSuffix(r.URL.Path, "/tag"):
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasSuffix(r.URL.Path, "/push"):
+			fmt.Fprintf(w, `{"status":"pushed with %s"}`, r.Header.Get("X-Registry-Auth"))
+		case r.URL.Path == "/images/get":
+			fmt.Fprint(w, "tarball")
+		}
+	})
+	defer server1.Close()
+	setImages(node1, "foo:latest", "bar:latest")
+	c.nodes[node1.ID()] = node1
+
+	node2, server2 := createPullNode(t, "node-2", func(w http.ResponseWriter, r *http.Request) {
+		if record("node-2", r) != "POST /images/load? archive" {
+			http.Error(w, "image is in use", http.StatusConflict)
+		}
+	})
+	defer server2.Close()
+	setImages(node2, "foo:latest")
+	c.nodes[node2.ID()] = node2
+
+	node3, server3 := createPullNode(t, "node-3", func(w http.ResponseWriter, r *http.Request) {
+		record("node-3", r)
+	})
+	defer server3.Close()
+	c.nodes[node3.ID()] = node3
+
+	// Tag on every node holding the image, reporting the failures.
+	results, err := c.TagImage("foo", "baz", "1.0", true)
+	assert.NoError(t, err)
+	assert.Equal(t, len(results), 2)
+	assert.Equal(t, resultOf(results, "node-1").Error, "")
+	assert.Equal(t, resultOf(results, "node-2").Error, "Conflict: image is in use")
+	assert.Equal(t, requests["node-1"], []string{"POST /images/foo/tag?force=1&repo=baz&tag=1.0 "})
+	assert.Equal(t, len(requests["node-3"]), 0)
+
+	_, err = c.TagImage("unknown", "baz", "", false)
+	assert.Equal(t, err, cluster.ErrImageNotFound)
+
+	// The images of the nodes are refreshed afterwards.
+	assert.Nil(t, node1.Image("foo"))
+
+	setImages(node1, "foo:latest")
+	results, err = c.RemoveImage("foo", false, true)
+	assert.NoError(t, err)
+	assert.Equal(t, len(results), 2)
+	assert.Equal(t, string(resultOf(results, "node-1").Body), `[{"Untagged":"foo:latest"},{"Deleted":"foo-id"}]`)
+	assert.Equal(t, resultOf(results, "node-2").Error, "Conflict: image is in use")
+
+	// The archive is copied to every node.
+	results, err = c.LoadImage(bytes.NewBufferString("archive"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, len(results), 3)
+	for _, name := range []string{"node-1", "node-2", "node-3"} {
+		assert.Equal(t, resultOf(results, name).Error, "")
+		assert.Equal(t, requests[name][len(requests[name])-1], "POST /images/load? archive")
+	}
+
+	// A truncated archive isn't loaded anywhere.
+	results, err = c.LoadImage(&failingReader{}, nil)
+	assert.NoError(t, err)
+	for _, result := range results {
+		assert.NotEqual(t, result.Error, "")
+	}
+
+	_, err = c.LoadImage(bytes.NewBufferString("archive"), []string{"storage==ssd"})
+	assert.Error(t, err)
+
+	// Push and save run on a node holding the images.
+	setImages(node1, "foo:latest", "bar:latest")
+	progress, err := c.PushImage("bar", "latest", "credentials")
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(progress)
+	assert.NoError(t, err)
+	progress.Close()
+	assert.Equal(t, string(data), `{"status":"pushed with credentials"}`)
+	assert.Equal(t, requests["node-1"][len(requests["node-1"])-1], "POST /images/bar/push?tag=latest ")
+
+	archive, err := c.SaveImages([]string{"foo", "bar"})
+	assert.NoError(t, err)
+	data, err = ioutil.ReadAll(archive)
+	assert.NoError(t, err)
+	archive.Close()
+	assert.Equal(t, string(data), "tarball")
+
+	_, err = c.SaveImages([]string{"foo", "unknown"})
+	assert.Equal(t, err, cluster.ErrImageNotFound)
+}
@@ -11,6 +11,8 @@ import (
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/discovery"
+	"github.com/docker/swarm/metrics"
 	"github.com/samalba/dockerclient"
 )
 
@@ -22,11 +24,17 @@ const (
 	requestTimeout = 10 * time.Second
 )
 
+var (
+	refreshDurationMetric = metrics.NewHistogram("swarm_node_refresh_duration_seconds", "Time spent refreshing the state of a node.", metrics.DefBuckets, "addr")
+	refreshErrorsMetric   = metrics.NewCounter("swarm_node_refresh_errors_total", "Number of failed refreshes of the state of a node.", "addr")
+)
+
 func NewNode(addr string, overcommitRatio float64) *node {
 	e := &node{
 		addr:            addr,
 		labels:          make(map[string]string),
 		ch:              make(chan bool),
+		stopCh:          make(chan struct{}),
 		containers:      make(map[string]*cluster.Container),
 		healthy:         true,
 		overcommitRatio: int64(overcommitRatio * 100),
@@ -45,12 +53,25 @@ type node struct {
 	Memory int64
 	labels map[string]string
 
+	// The labels of the engine, and the metadata of the node in the
+	// discovery service.
+	engineLabels map[string]string
+	entryLabels  map[string]string
+	weight       int64
+
 	ch              chan bool
+	stopCh          chan struct{}
 	containers      map[string]*cluster.Container
 	images          []*cluster.Image
 	client          dockerclient.Client
 	eventHandler    cluster.EventHandler
 	healthy         bool
+	cordoned        bool
+	warming         bool
+	version         string
+	lastRefresh     time.Time
+	lastError       error
+	tlsConfig       *tls.Config
 	overcommitRatio int64
 }
 
@@ -71,6 +92,9 @@ func (n *node) Name() string {
 }
 
 func (n *node) Labels() map[string]string {
+	n.RLock()
+	defer n.RUnlock()
+
 	return n.labels
 }
 
@@ -87,6 +111,7 @@ func (n *node) connect(config *tls.Config) error {
 		return err
 	}
 	n.ip = addr.IP.String()
+	n.tlsConfig = config
 
 	c, err := dockerclient.NewDockerClientTimeout("tcp://"+n.addr, config, time.Duration(requestTimeout))
 	if err != nil {
@@ -115,6 +140,7 @@ func (n *node) connectClient(client dockerclient.Client) error {
 		n.client = nil
 		return err
 	}
+	n.lastRefresh = time.Now()
 
 	// Start the update loop.
 	go n.refreshLoop()
@@ -150,7 +176,7 @@ func (n *node) updateSpecs() error {
 	n.name = info.Name
 	n.Cpus = info.NCPU
 	n.Memory = info.MemTotal
-	n.labels = map[string]string{
+	labels := map[string]string{
 		"storagedriver":   info.Driver,
 		"executiondriver": info.ExecutionDriver,
 		"kernelversion":   info.KernelVersion,
@@ -158,11 +184,73 @@ func (n *node) updateSpecs() error {
 	}
 	for _, label := range info.Labels {
 		kv := strings.SplitN(label, "=", 2)
-		n.labels[kv[0]] = kv[1]
+		labels[kv[0]] = kv[1]
+	}
+	n.Lock()
+	n.engineLabels = labels
+	n.mergeLabels()
+	n.Unlock()
+
+	// The version is only reported, don't refuse the node without it.
+	engineVersion := ""
+	if version, err := n.client.Version(); err != nil {
+		log.WithField("name", n.name).Warnf("Unable to get the engine version: %v", err)
+	} else {
+		engineVersion = version.Version
 	}
+	n.Lock()
+	n.version = engineVersion
+	n.Unlock()
 	return nil
 }
 
+// Set the metadata of the node from its discovery entry.
+func (n *node) setEntry(entry *discovery.Entry) {
+	n.Lock()
+	defer n.Unlock()
+
+	n.entryLabels = entry.Labels
+	n.weight = entry.Weight
+	n.mergeLabels()
+}
+
+// Merge the labels of the discovery entry over the ones of the engine, so
+// that hosts can be labeled without restarting their daemon. The caller must
+// hold the node lock.
+func (n *node) mergeLabels() {
+	labels := make(map[string]string)
+	for key, value := range n.engineLabels {
+		labels[key] = value
+	}
+	for key, value := range n.entryLabels {
+		labels[key] = value
+	}
+	n.labels = labels
+}
+
+// Return the share of the containers the node gets from the spread strategy,
+// relative to the other nodes.
+func (n *node) Weight() int64 {
+	n.RLock()
+	defer n.RUnlock()
+
+	if n.weight <= 0 {
+		return 1
+	}
+	return n.weight
+}
+
+// Return `config` verifying the certificate of the node against
+// `serverName` rather than its host.
+func withServerName(config *tls.Config, serverName string) *tls.Config {
+	if config == nil || serverName == "" {
+		return config
+	}
+	named := config.Clone()
+	named.ServerName = serverName
+	return named
+}
+
 // Refresh the list of images on the node.
 func (n *node) refreshImages() error {
 	images, err := n.client.ListImages()
@@ -266,22 +354,35 @@ func (n *node) updateContainer(c dockerclient.Container, containers map[string]*
 }
 
 func (n *node) refreshContainersAsync() {
-	n.ch <- true
+	select {
+	case n.ch <- true:
+	case <-n.stopCh:
+	}
 }
 
 func (n *node) refreshLoop() {
 	for {
-		var err error
 		select {
 		case <-n.ch:
-			err = n.refreshContainers(false)
 		case <-time.After(stateRefreshPeriod):
-			err = n.refreshContainers(false)
+		case <-n.stopCh:
+			return
 		}
 
+		start := time.Now()
+		err := n.refreshContainers(false)
 		if err == nil {
 			err = n.refreshImages()
 		}
+		refreshDurationMetric.Since(start, n.addr)
+		if err != nil {
+			refreshErrorsMetric.Inc(n.addr)
+		}
+
+		n.Lock()
+		n.lastRefresh = time.Now()
+		n.lastError = err
+		n.Unlock()
 
 		if err != nil {
 			if n.healthy {
@@ -304,6 +405,85 @@ func (n *node) refreshLoop() {
 	}
 }
 
+// Stop refreshing the node and monitoring its events, once it left the
+// cluster.
+func (n *node) stop() {
+	n.Lock()
+	defer n.Unlock()
+
+	select {
+	case <-n.stopCh:
+		return
+	default:
+	}
+	close(n.stopCh)
+	if n.client != nil {
+		n.client.StopAllMonitorEvents()
+	}
+}
+
+// Return whether the node is kept out of scheduling.
+func (n *node) IsCordoned() bool {
+	n.RLock()
+	defer n.RUnlock()
+
+	return n.cordoned
+}
+
+// Keep the node out of scheduling, or bring it back. Return false if the
+// node already was in the requested state.
+func (n *node) setCordoned(cordoned bool) bool {
+	n.Lock()
+	changed := n.cordoned != cordoned
+	n.cordoned = cordoned
+	n.Unlock()
+
+	if changed {
+		if cordoned {
+			n.emitEvent("node_cordon")
+		} else {
+			n.emitEvent("node_uncordon")
+		}
+	}
+	return changed
+}
+
+// Return the status of the node.
+func (n *node) describe() *cluster.NodeStatus {
+	status := &cluster.NodeStatus{
+		ID:              n.id,
+		Name:            n.name,
+		Addr:            n.addr,
+		IP:              n.ip,
+		Labels:          make(map[string]string),
+		Healthy:         n.IsHealthy(),
+		Cordoned:        n.IsCordoned(),
+		Warming:         n.isWarming(),
+		Cpus:            n.Cpus,
+		Memory:          n.Memory,
+		OvercommitRatio: float64(n.overcommitRatio) / 100,
+		TotalCpus:       n.TotalCpus(),
+		TotalMemory:     n.TotalMemory(),
+		UsedCpus:        n.UsedCpus(),
+		UsedMemory:      n.UsedMemory(),
+		Weight:          n.Weight(),
+		Containers:      len(n.Containers()),
+		Images:          len(n.Images()),
+	}
+
+	n.RLock()
+	defer n.RUnlock()
+	for key, value := range n.labels {
+		status.Labels[key] = value
+	}
+	status.EngineVersion = n.version
+	status.LastRefresh = n.lastRefresh
+	if n.lastError != nil {
+		status.LastError = n.lastError.Error()
+	}
+	return status
+}
+
 func (n *node) emitEvent(event string) {
 	// If there is no event handler registered, abort right now.
 	if n.eventHandler == nil {
@@ -387,6 +567,16 @@ func (n *node) create(config *dockerclient.ContainerConfig, name string, pullIma
 	return n.containers[id], nil
 }
 
+// Start a container previously created on the node.
+func (n *node) start(container *cluster.Container, hostConfig *dockerclient.HostConfig) error {
+	if err := n.client.StartContainer(container.Id, hostConfig); err != nil {
+		return err
+	}
+
+	// Pick up the new state (NetworkSettings, ...) of the container.
+	return n.refreshContainer(container.Id, true)
+}
+
 // Destroy and remove a container from the node.
 func (n *node) destroy(container *cluster.Container, force bool) error {
 	if err := n.client.RemoveContainer(container.Id, force, true); err != nil {
@@ -1,10 +1,12 @@
 package swarm
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/docker/swarm/discovery"
 	"github.com/samalba/dockerclient"
 	"github.com/samalba/dockerclient/mockclient"
 	"github.com/stretchr/testify/assert"
@@ -23,6 +25,10 @@ var (
 		OperatingSystem: "golang",
 		Labels:          []string{"foo=bar"},
 	}
+
+	mockVersion = &dockerclient.Version{
+		Version: "1.6.0",
+	}
 )
 
 func TestNodeConnectionFailure(t *testing.T) {
@@ -57,6 +63,7 @@ func TestNodeCpusMemory(t *testing.T) {
 
 	client := mockclient.NewMockClient()
 	client.On("Info").Return(mockInfo, nil)
+	client.On("Version").Return(mockVersion, nil)
 	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{}, nil)
 	client.On("ListImages").Return([]*dockerclient.Image{}, nil)
 	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
@@ -77,6 +84,7 @@ func TestNodeSpecs(t *testing.T) {
 
 	client := mockclient.NewMockClient()
 	client.On("Info").Return(mockInfo, nil)
+	client.On("Version").Return(mockVersion, nil)
 	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{}, nil)
 	client.On("ListImages").Return([]*dockerclient.Image{}, nil)
 	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
@@ -92,16 +100,81 @@ func TestNodeSpecs(t *testing.T) {
 	assert.Equal(t, node.Labels()["kernelversion"], mockInfo.KernelVersion)
 	assert.Equal(t, node.Labels()["operatingsystem"], mockInfo.OperatingSystem)
 	assert.Equal(t, node.Labels()["foo"], "bar")
+	assert.Equal(t, node.version, mockVersion.Version)
 
 	client.Mock.AssertExpectations(t)
 }
 
+func TestNodeVersionFailure(t *testing.T) {
+	node := NewNode("test", 0)
+
+	client := mockclient.NewMockClient()
+	client.On("Info").Return(mockInfo, nil)
+	client.On("Version").Return((*dockerclient.Version)(nil), errors.New("version unavailable"))
+	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{}, nil)
+	client.On("ListImages").Return([]*dockerclient.Image{}, nil)
+	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+
+	// The node is still connected, without a version.
+	assert.NoError(t, node.connectClient(client))
+	assert.True(t, node.isConnected())
+	assert.Equal(t, node.version, "")
+
+	client.Mock.AssertExpectations(t)
+}
+
+func TestNodeEntry(t *testing.T) {
+	node := NewNode("test", 0)
+	assert.Equal(t, node.Weight(), 1)
+
+	entry, err := discovery.ParseEntry("test:2375 foo=baz zone=us-east weight=2")
+	assert.NoError(t, err)
+	node.setEntry(entry)
+	assert.Equal(t, node.Weight(), 2)
+
+	client := mockclient.NewMockClient()
+	client.On("Info").Return(mockInfo, nil)
+	client.On("Version").Return(mockVersion, nil)
+	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{}, nil)
+	client.On("ListImages").Return([]*dockerclient.Image{}, nil)
+	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+	assert.NoError(t, node.connectClient(client))
+
+	// The labels of the entry win over the ones of the engine.
+	assert.Equal(t, node.Labels()["storagedriver"], mockInfo.Driver)
+	assert.Equal(t, node.Labels()["foo"], "baz")
+	assert.Equal(t, node.Labels()["zone"], "us-east")
+
+	// The engine labels are back once the entry drops its own.
+	entry, err = discovery.ParseEntry("test:2375")
+	assert.NoError(t, err)
+	node.setEntry(entry)
+	assert.Equal(t, node.Labels()["foo"], "bar")
+	_, exists := node.Labels()["zone"]
+	assert.False(t, exists)
+	assert.Equal(t, node.Weight(), 1)
+}
+
+func TestWithServerName(t *testing.T) {
+	assert.Nil(t, withServerName(nil, "node-1"))
+
+	config := &tls.Config{InsecureSkipVerify: true, CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}}
+	assert.Equal(t, withServerName(config, ""), config)
+
+	named := withServerName(config, "node-1")
+	assert.Equal(t, named.ServerName, "node-1")
+	assert.True(t, named.InsecureSkipVerify)
+	assert.Equal(t, named.CipherSuites, config.CipherSuites)
+	assert.Empty(t, config.ServerName)
+}
+
 func TestNodeState(t *testing.T) {
 	node := NewNode("test", 0)
 	assert.False(t, node.isConnected())
 
 	client := mockclient.NewMockClient()
 	client.On("Info").Return(mockInfo, nil)
+	client.On("Version").Return(mockVersion, nil)
 	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
 
 	// The client will return one container at first, then a second one will appear.
@@ -141,6 +214,7 @@ func TestNodeContainerLookup(t *testing.T) {
 
 	client := mockclient.NewMockClient()
 	client.On("Info").Return(mockInfo, nil)
+	client.On("Version").Return(mockVersion, nil)
 	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
 
 	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{{Id: "container-id", Names: []string{"/container-name1", "/container-name2"}}}, nil).Once()
@@ -180,6 +254,7 @@ func TestCreateContainer(t *testing.T) {
 	)
 
 	client.On("Info").Return(mockInfo, nil)
+	client.On("Version").Return(mockVersion, nil)
 	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
 	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{}, nil).Once()
 	client.On("ListImages").Return([]*dockerclient.Image{}, nil).Once()
@@ -244,3 +319,37 @@ func TestTotalCpus(t *testing.T) {
 	node.Cpus = 2
 	assert.Equal(t, node.TotalCpus(), 2)
 }
+
+func TestNodeDescribe(t *testing.T) {
+	node := NewNode("test", 0.05)
+
+	client := mockclient.NewMockClient()
+	client.On("Info").Return(mockInfo, nil)
+	client.On("Version").Return(mockVersion, nil)
+	client.On("ListContainers", true, false, "").Return([]dockerclient.Container{{Id: "one"}}, nil)
+	client.On("InspectContainer", "one").Return(&dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{}}, nil)
+	client.On("ListImages").Return([]*dockerclient.Image{{Id: "image"}}, nil)
+	client.On("StartMonitorEvents", mock.Anything, mock.Anything, mock.Anything).Return()
+	assert.NoError(t, node.connectClient(client))
+
+	status := node.describe()
+	assert.Equal(t, status.ID, mockInfo.ID)
+	assert.Equal(t, status.Name, mockInfo.Name)
+	assert.Equal(t, status.Addr, "test")
+	assert.Equal(t, status.Labels["foo"], "bar")
+	assert.True(t, status.Healthy)
+	assert.False(t, status.Cordoned)
+	assert.False(t, status.LastRefresh.IsZero())
+	assert.Empty(t, status.LastError)
+	assert.Equal(t, status.Cpus, mockInfo.NCPU)
+	assert.Equal(t, status.OvercommitRatio, 0.05)
+	assert.Equal(t, status.TotalCpus, node.TotalCpus())
+	assert.Equal(t, status.UsedCpus, node.UsedCpus())
+	assert.Equal(t, status.Containers, 1)
+	assert.Equal(t, status.Images, 1)
+	assert.Equal(t, status.EngineVersion, mockVersion.Version)
+
+	// The status is a copy.
+	status.Labels["foo"] = "baz"
+	assert.Equal(t, node.Labels()["foo"], "bar")
+}
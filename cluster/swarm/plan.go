@@ -0,0 +1,84 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/state"
+)
+
+var ErrInvalidPlan = errors.New("every plan request needs a Config, and a positive Count when set")
+
+// Return the names of the containers of `request`.
+func planNames(request *cluster.PlanRequest, index int) []string {
+	count := request.Count
+	if count == 0 {
+		count = 1
+	}
+
+	name := request.Name
+	if name == "" {
+		name = fmt.Sprintf("request-%d", index+1)
+	}
+	if count == 1 && request.Name != "" {
+		return []string{name}
+	}
+
+	names := []string{}
+	for i := 1; i <= count; i++ {
+		names = append(names, fmt.Sprintf("%s-%d", name, i))
+	}
+	return names
+}
+
+// Place the containers of `requests` one after another on a simulated copy
+// of the cluster, each placement reserving resources for the next ones.
+// Nothing is created on the engines.
+func (c *Cluster) Plan(requests []*cluster.PlanRequest) (*cluster.Plan, error) {
+	for _, request := range requests {
+		if request.Config == nil || request.Count < 0 {
+			return nil, ErrInvalidPlan
+		}
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	var (
+		plan   = &cluster.Plan{Placements: []*cluster.Placement{}}
+		nodes  = []cluster.Node{}
+		dryRun = c.scheduler.DryRun()
+	)
+	for _, n := range simulateNodes(c.Nodes()) {
+		nodes = append(nodes, n)
+	}
+
+	for i, request := range requests {
+		// Global containers get a copy on every node accepted by the filters.
+		if schedulingMode(request.Config) == state.ModeGlobal {
+			name := planNames(&cluster.PlanRequest{Name: request.Name}, i)[0]
+			accepted, err := dryRun.FilterNodes(nodes, request.Config)
+			if err != nil {
+				plan.Failed, plan.Error = name, err.Error()
+				return plan, nil
+			}
+			for _, n := range accepted {
+				n.(*simulatedNode).add(name, request.Config)
+				plan.Placements = append(plan.Placements, &cluster.Placement{Name: name, Node: n.Name()})
+			}
+			continue
+		}
+
+		for _, name := range planNames(request, i) {
+			n, _, err := c.scheduleWith(dryRun, nodes, request.Config)
+			if err != nil {
+				plan.Failed, plan.Error = name, err.Error()
+				return plan, nil
+			}
+			n.(*simulatedNode).add(name, request.Config)
+			plan.Placements = append(plan.Placements, &cluster.Placement{Name: name, Node: n.Name()})
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,64 @@
+package swarm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/strategy"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlan(t *testing.T) {
+	c := &Cluster{
+		nodes:     make(map[string]*node),
+		scheduler: scheduler.New(&strategy.SpreadPlacementStrategy{}, []filter.Filter{&filter.HealthFilter{}, &filter.ConstraintFilter{}, &filter.PortFilter{}}),
+	}
+	for i := 0; i < 2; i++ {
+		n := createNode(t, fmt.Sprintf("node-%d", i))
+		n.Cpus = 4
+		n.labels = map[string]string{}
+		c.nodes[n.id] = n
+	}
+
+	_, err := c.Plan([]*cluster.PlanRequest{{Name: "web"}})
+	assert.Equal(t, err, ErrInvalidPlan)
+	_, err = c.Plan([]*cluster.PlanRequest{{Name: "web", Count: -1, Config: &dockerclient.ContainerConfig{}}})
+	assert.Equal(t, err, ErrInvalidPlan)
+
+	// The reservations of each placement are taken into account by the next ones.
+	plan, err := c.Plan([]*cluster.PlanRequest{
+		{Name: "web", Count: 3, Config: &dockerclient.ContainerConfig{CpuShares: 2}},
+		{Name: "db", Config: &dockerclient.ContainerConfig{CpuShares: 1}},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, plan.Failed)
+	assert.Len(t, plan.Placements, 4)
+	assert.Equal(t, plan.Placements[0].Name, "web-1")
+	assert.NotEqual(t, plan.Placements[0].Node, plan.Placements[1].Node)
+	assert.Equal(t, plan.Placements[3].Name, "db")
+	assert.Equal(t, plan.Placements[3].Node, plan.Placements[1].Node)
+
+	// The placement stops at the first container that fits nowhere.
+	plan, err = c.Plan([]*cluster.PlanRequest{{Name: "web", Count: 5, Config: &dockerclient.ContainerConfig{CpuShares: 2}}})
+	assert.NoError(t, err)
+	assert.Len(t, plan.Placements, 4)
+	assert.Equal(t, plan.Failed, "web-5")
+	assert.Equal(t, plan.Error, strategy.ErrNoResourcesAvailable.Error())
+
+	// Simulated containers hold their ports.
+	ports := &dockerclient.ContainerConfig{HostConfig: dockerclient.HostConfig{PortBindings: map[string][]dockerclient.PortBinding{"80/tcp": {{HostPort: "80"}}}}}
+	plan, err = c.Plan([]*cluster.PlanRequest{{Name: "lb", Count: 3, Config: ports}})
+	assert.NoError(t, err)
+	assert.Len(t, plan.Placements, 2)
+	assert.Equal(t, plan.Failed, "lb-3")
+
+	// Nothing was created.
+	for _, n := range c.nodes {
+		assert.Empty(t, n.Containers())
+		assert.Equal(t, n.UsedCpus(), 0)
+	}
+}
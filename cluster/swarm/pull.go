@@ -0,0 +1,116 @@
+package swarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/samalba/dockerclient"
+)
+
+// Pull `image` on the node, calling `callback` with every progress message.
+// The engine client doesn't report the progress, the pull is sent as is.
+func (n *node) pullProgress(image string, callback func(*cluster.PullProgress)) error {
+	var (
+		client = &http.Client{}
+		scheme = "http"
+	)
+	if n.tlsConfig != nil {
+		client.Transport = &http.Transport{TLSClientConfig: n.tlsConfig}
+		scheme = "https"
+	}
+
+	query := url.Values{}
+	query.Set("fromImage", image)
+	resp, err := client.Post(scheme+"://"+n.addr+"/images/create?"+query.Encode(), "text/plain", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s pulling %s", resp.Status, image)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		progress := &cluster.PullProgress{}
+		if err := decoder.Decode(progress); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if progress.Error != "" {
+			return fmt.Errorf("%s", progress.Error)
+		}
+		progress.Node = n
+		callback(progress)
+	}
+}
+
+// Pull an image on every healthy node, or on the ones matching
+// `constraints`, `options.PullParallelism` nodes at a time. A node failing
+// is reported through `callback` without stopping the others.
+func (c *Cluster) Pull(name string, constraints []string, callback func(*cluster.PullProgress)) error {
+	config := &dockerclient.ContainerConfig{}
+	for _, constraint := range constraints {
+		config.Env = append(config.Env, "constraint:"+constraint)
+	}
+	nodes, err := filter.ApplyFilters([]filter.Filter{&filter.HealthFilter{}, &filter.ConstraintFilter{}}, config, c.Nodes())
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		slot chan bool
+	)
+	if c.options.PullParallelism > 0 {
+		slot = make(chan bool, c.options.PullParallelism)
+	}
+	report := func(progress *cluster.PullProgress) {
+		if callback == nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		callback(progress)
+	}
+
+	for _, n := range nodes {
+		nn, ok := n.(*node)
+		if !ok {
+			continue
+		}
+
+		wg.Add(1)
+		go func(n *node) {
+			defer wg.Done()
+			if slot != nil {
+				slot <- true
+				defer func() { <-slot }()
+			}
+
+			report(&cluster.PullProgress{Node: n, Status: "Pulling " + name + "..."})
+			if err := n.pullProgress(name, report); err != nil {
+				log.WithFields(log.Fields{"name": n.name, "image": name}).Errorf("Unable to pull image: %v", err)
+				report(&cluster.PullProgress{Node: n, Error: strings.TrimSpace(err.Error())})
+				return
+			}
+			n.refreshImages()
+			report(&cluster.PullProgress{Node: n, Status: "Downloaded " + name})
+		}(nn)
+	}
+	wg.Wait()
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package swarm
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// Create a node served by `handler`.
+func createPullNode(t *testing.T, ID string, handler http.HandlerFunc) (*node, *httptest.Server) {
+	server := httptest.NewServer(handler)
+
+	client := mockclient.NewMockClient()
+	client.On("ListImages").Return([]*dockerclient.Image{}, nil)
+
+	n := createNode(t, ID)
+	n.addr = strings.TrimPrefix(server.URL, "http://")
+	n.client = client
+	return n, server
+}
+
+func TestPull(t *testing.T) {
+	c := &Cluster{
+		nodes:   make(map[string]*node),
+		options: &cluster.Options{PullParallelism: 1},
+	}
+
+	node1, server1 := createPullNode(t, "node-1", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Query().Get("fromImage"), "busybox:latest")
+		fmt.Fprintln(w, `{"status":"Pulling fs layer","progressDetail":{},"id":"layer"}`)
+		fmt.Fprintln(w, `{"status":"Downloading","progressDetail":{"current":10,"total":20},"progress":"[=====>     ]","id":"layer"}`)
+	})
+	defer server1.Close()
+	c.nodes[node1.ID()] = node1
+
+	node2, server2 := createPullNode(t, "node-2", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"errorDetail":{"message":"no space left"},"error":"no space left"}`)
+	})
+	defer server2.Close()
+	node2.labels["storage"] = "ssd"
+	c.nodes[node2.ID()] = node2
+
+	progresses := map[string][]*cluster.PullProgress{}
+	callback := func(progress *cluster.PullProgress) {
+		progresses[progress.Node.Name()] = append(progresses[progress.Node.Name()], progress)
+	}
+
+	// A node failing doesn't stop the others.
+	assert.NoError(t, c.Pull("busybox:latest", nil, callback))
+	assert.Equal(t, len(progresses["node-1"]), 4)
+	assert.Equal(t, progresses["node-1"][2].ID, "layer")
+	assert.Equal(t, progresses["node-1"][2].ProgressDetail.Current, int64(10))
+	assert.Equal(t, progresses["node-1"][2].ProgressDetail.Total, int64(20))
+	assert.Equal(t, progresses["node-1"][3].Status, "Downloaded busybox:latest")
+
+	assert.Equal(t, len(progresses["node-2"]), 2)
+	assert.Equal(t, progresses["node-2"][1].Error, "no space left")
+
+	// Only pull on the nodes matching the constraints.
+	progresses = map[string][]*cluster.PullProgress{}
+	assert.NoError(t, c.Pull("busybox:latest", []string{"storage==ssd"}, callback))
+	assert.Equal(t, len(progresses["node-1"]), 0)
+	assert.Equal(t, len(progresses["node-2"]), 2)
+
+	// No node matching the constraints.
+	assert.Error(t, c.Pull("busybox:latest", []string{"storage==disk"}, callback))
+}
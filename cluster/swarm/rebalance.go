@@ -0,0 +1,171 @@
+package swarm
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/state"
+	"github.com/samalba/dockerclient"
+)
+
+var ErrInvalidRatio = errors.New("the rebalancing ratio must be between 0 and 1")
+
+// Return whether the `rebalance:<bool>` env hint allows moving the container.
+// Containers without hint can only move if `optIn` is false.
+func isMovable(config *dockerclient.ContainerConfig, optIn bool) bool {
+	for _, env := range config.Env {
+		switch env {
+		case "rebalance:true":
+			return true
+		case "rebalance:false":
+			return false
+		}
+	}
+	return !optIn
+}
+
+// A container planned to move, and where.
+type migration struct {
+	state     *state.RequestedState
+	container *cluster.Container
+	from      *node
+	to        *node
+}
+
+// Sort the candidates to a move by decreasing reservation.
+type bySize []*cluster.Container
+
+func (s bySize) Len() int      { return len(s) }
+func (s bySize) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySize) Less(i, j int) bool {
+	return s[i].Info.Config.CpuShares+s[i].Info.Config.Memory > s[j].Info.Config.CpuShares+s[j].Info.Config.Memory
+}
+
+// Move containers off the nodes reserved beyond `options.Ratio`, onto the nodes
+// the scheduler chooses among the ones staying under it.
+func (c *Cluster) Rebalance(options *cluster.RebalanceOptions) ([]*cluster.Move, error) {
+	if options.Ratio <= 0 || options.Ratio > 1 {
+		return nil, ErrInvalidRatio
+	}
+
+	plan := c.planRebalance(options)
+
+	moves := []*cluster.Move{}
+	for _, m := range plan {
+		move := &cluster.Move{
+			ID:   m.state.ID,
+			Name: m.state.Name,
+			From: m.from.Name(),
+			To:   m.to.Name(),
+		}
+		if !options.DryRun {
+			if err := c.migrate(m, "container_rebalanced"); err != nil {
+				log.WithFields(log.Fields{"id": m.state.ID, "from": move.From, "to": move.To}).Errorf("Unable to move container: %v", err)
+				move.Error = err.Error()
+			}
+		}
+		moves = append(moves, move)
+	}
+	return moves, nil
+}
+
+// Compute the moves bringing every node under the ratio, as far as possible.
+func (c *Cluster) planRebalance(options *cluster.RebalanceOptions) []*migration {
+	var (
+		states = make(map[string]*state.RequestedState)
+		nodes  = simulateNodes(c.healthyNodes())
+		plan   = []*migration{}
+	)
+
+	for _, st := range c.store.All() {
+		// Global containers already run everywhere they can.
+		if st.Mode != state.ModeGlobal {
+			states[st.Engine()] = st
+		}
+	}
+
+	for _, from := range nodes {
+		if from.ratio() <= options.Ratio {
+			continue
+		}
+
+		candidates := []*cluster.Container{}
+		for _, container := range from.Containers() {
+			if st, exists := states[container.Id]; exists && container.Info.Config != nil && isMovable(st.Config, options.OptIn) {
+				candidates = append(candidates, container)
+			}
+		}
+		sort.Sort(bySize(candidates))
+
+		for _, container := range candidates {
+			if from.ratio() <= options.Ratio {
+				break
+			}
+
+			st := states[container.Id]
+			to := c.placeSimulated(st.Config, nodes, from, options.Ratio)
+			if to == nil {
+				continue
+			}
+
+			from.remove(container)
+			to.add(st.Name, st.Config)
+			plan = append(plan, &migration{state: st, container: container, from: from.node, to: to.node})
+		}
+	}
+
+	return plan
+}
+
+// Return the node the scheduler would choose for `config` among the nodes,
+// other than `from`, staying under the ratio with it.
+func (c *Cluster) placeSimulated(config *dockerclient.ContainerConfig, nodes []*simulatedNode, from *simulatedNode, ratio float64) *simulatedNode {
+	candidates := []cluster.Node{}
+	for _, n := range nodes {
+		if n == from {
+			continue
+		}
+		if reservedRatio(n.UsedCpus()+config.CpuShares, n.TotalCpus(), n.UsedMemory()+config.Memory, n.TotalMemory()) > ratio {
+			continue
+		}
+		candidates = append(candidates, n)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	n, err := c.selectNode(candidates, config)
+	if err != nil {
+		return nil
+	}
+	to, _ := n.(*simulatedNode)
+	return to
+}
+
+// Re-create the container on its new node, then remove the old one. `status`
+// is the event emitted once done.
+func (c *Cluster) migrate(m *migration, status string) error {
+	container, err := c.recreate(m.state, m.container, m.to)
+	if err != nil {
+		return err
+	}
+
+	if err := m.from.destroy(m.container, true); err != nil {
+		log.WithFields(log.Fields{"name": m.from.Name(), "id": m.container.Id}).Errorf("Unable to remove moved container: %v", err)
+	}
+
+	log.WithFields(log.Fields{"id": m.state.ID, "from": m.from.Name(), "to": m.to.Name()}).Info("Container moved")
+	c.Handle(&cluster.Event{
+		Event: dockerclient.Event{
+			Status: status,
+			Id:     container.Id,
+			From:   "swarm",
+			Time:   time.Now().Unix(),
+		},
+		Node: m.to,
+	})
+	return nil
+}
@@ -0,0 +1,113 @@
+package swarm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/strategy"
+	"github.com/docker/swarm/state"
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Add a container reserving `cpus` to `n` and to the store.
+func addReservedContainer(t *testing.T, store state.Store, n *node, id string, cpus int64, env ...string) {
+	config := &dockerclient.ContainerConfig{Image: "busybox", CpuShares: cpus, Env: env}
+	container := &cluster.Container{
+		Container: dockerclient.Container{Id: id, Names: []string{"/" + id}, Status: "Up 2 hours"},
+		Info:      dockerclient.ContainerInfo{Config: config},
+		Node:      n,
+	}
+	n.addContainer(container)
+	assert.NoError(t, store.Add("swarm-"+id, &state.RequestedState{ID: "swarm-" + id, EngineID: id, Name: id, Config: config}))
+}
+
+func TestRebalance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rebalance-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := state.NewFileStore(dir)
+	assert.NoError(t, store.Initialize())
+
+	c := &Cluster{
+		eventHandler: &FakeEventHandler{},
+		nodes:        make(map[string]*node),
+		scheduler:    scheduler.New(&strategy.BinPackingPlacementStrategy{}, []filter.Filter{&filter.HealthFilter{}}),
+		options:      &cluster.Options{},
+		store:        store,
+	}
+
+	// node-1 is overcommitted, node-2 is empty.
+	node1 := createNode(t, "node-1")
+	node1.Cpus = 4
+	c.nodes[node1.ID()] = node1
+	addReservedContainer(t, store, node1, "big", 2)
+	addReservedContainer(t, store, node1, "small", 1)
+	addReservedContainer(t, store, node1, "pinned", 2, "rebalance:false")
+
+	client := mockclient.NewMockClient()
+	node2 := createNode(t, "node-2")
+	node2.client = client
+	node2.Cpus = 4
+	c.nodes[node2.ID()] = node2
+
+	// Invalid ratios are rejected.
+	_, err = c.Rebalance(&cluster.RebalanceOptions{Ratio: 0})
+	assert.Equal(t, err, ErrInvalidRatio)
+	_, err = c.Rebalance(&cluster.RebalanceOptions{Ratio: 1.5})
+	assert.Equal(t, err, ErrInvalidRatio)
+
+	// Moving the biggest container is enough to get under the ratio.
+	moves, err := c.Rebalance(&cluster.RebalanceOptions{Ratio: 0.8, DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, len(moves), 1)
+	assert.Equal(t, moves[0].ID, "swarm-big")
+	assert.Equal(t, moves[0].From, "node-1")
+	assert.Equal(t, moves[0].To, "node-2")
+
+	// Nothing is moved in dry-run mode.
+	assert.NotNil(t, node1.Container("big"))
+
+	// Pinned containers never move, even if node-1 stays over the ratio.
+	moves, err = c.Rebalance(&cluster.RebalanceOptions{Ratio: 0.5, DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, len(moves), 1)
+	assert.Equal(t, moves[0].ID, "swarm-big")
+
+	// In opt-in mode, containers without hint stay where they are.
+	moves, err = c.Rebalance(&cluster.RebalanceOptions{Ratio: 0.8, OptIn: true, DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, len(moves), 0)
+
+	// Otherwise the container is re-created on node-2 and removed from node-1.
+	client.On("CreateContainer", mock.Anything, "big").Return("new-big", nil).Once()
+	client.On("ListContainers", true, false, fmt.Sprintf("{%q:[%q]}", "id", "new-big")).Return([]dockerclient.Container{{Id: "new-big", Names: []string{"/big"}}}, nil)
+	client.On("InspectContainer", "new-big").Return(&dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{CpuShares: 50}}, nil)
+	client.On("StartContainer", "new-big", mock.Anything).Return(nil).Once()
+
+	old := mockclient.NewMockClient()
+	old.On("RemoveContainer", "big", true, true).Return(nil).Once()
+	node1.client = old
+
+	moves, err = c.Rebalance(&cluster.RebalanceOptions{Ratio: 0.8})
+	assert.NoError(t, err)
+	assert.Equal(t, len(moves), 1)
+	assert.Equal(t, moves[0].Error, "")
+	assert.Nil(t, node1.Container("big"))
+	assert.NotNil(t, node2.Container("new-big"))
+
+	st, err := store.Get("swarm-big")
+	assert.NoError(t, err)
+	assert.Equal(t, st.EngineID, "new-big")
+
+	client.Mock.AssertExpectations(t)
+	old.Mock.AssertExpectations(t)
+}
@@ -0,0 +1,210 @@
+package swarm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/state"
+	"github.com/samalba/dockerclient"
+)
+
+// Compare the requested state against the cluster this often.
+const reconcilePeriod = 5 * time.Second
+
+// reconcileLoop periodically re-creates the containers of the store that are
+// no longer running on any healthy node.
+func (c *Cluster) reconcileLoop() {
+	for now := range time.Tick(reconcilePeriod) {
+		if !c.isLeader() {
+			// Start over if we ever take the lead.
+			c.pending = make(map[string]time.Time)
+			continue
+		}
+		c.reconcile(now)
+	}
+}
+
+// reconcile compares the store to the containers known by the healthy nodes.
+// Containers missing for longer than the grace period are rescheduled.
+func (c *Cluster) reconcile(now time.Time) {
+	grace := time.Duration(c.options.RescheduleGrace) * time.Second
+	known := make(map[string]bool)
+
+	c.removeStale()
+
+	states := c.store.All()
+	// Pick up the changes made by the other managers sharing the store.
+	c.loadIDs(states)
+
+	for _, st := range states {
+		// Global containers already run everywhere they can.
+		if st.Mode == state.ModeGlobal {
+			continue
+		}
+		known[st.ID] = true
+
+		if c.isRunning(st.Engine()) {
+			delete(c.pending, st.ID)
+			continue
+		}
+
+		since, exists := c.pending[st.ID]
+		if !exists {
+			log.WithField("id", st.ID).Debug("Container is missing from the healthy nodes")
+			c.pending[st.ID] = now
+			continue
+		}
+		if now.Sub(since) < grace {
+			continue
+		}
+
+		if err := c.reschedule(st); err != nil {
+			// Keep it pending, we will try again on the next pass.
+			log.WithField("id", st.ID).Errorf("Unable to reschedule container: %v", err)
+			continue
+		}
+		delete(c.pending, st.ID)
+	}
+
+	// Forget about containers that were removed in the meantime.
+	for id := range c.pending {
+		if !known[id] {
+			delete(c.pending, id)
+		}
+	}
+}
+
+// removeStale removes the rescheduled containers reported again by their node
+// once it recovered, so they don't run twice. They are forgotten once removed,
+// or once their node left the cluster.
+func (c *Cluster) removeStale() {
+	for engineID, nodeID := range c.stale {
+		c.RLock()
+		n, exists := c.nodes[nodeID]
+		c.RUnlock()
+		if !exists {
+			delete(c.stale, engineID)
+			continue
+		}
+
+		container := n.Container(engineID)
+		if !n.IsHealthy() || container == nil {
+			continue
+		}
+		if err := n.destroy(container, true); err != nil {
+			// Try again on the next pass.
+			log.WithFields(log.Fields{"name": n.name, "id": engineID}).Errorf("Unable to remove rescheduled container: %v", err)
+			continue
+		}
+		log.WithFields(log.Fields{"name": n.name, "id": engineID}).Info("Removed rescheduled container")
+		delete(c.stale, engineID)
+	}
+}
+
+// isRunning returns true if the container `id` is known by a healthy node.
+func (c *Cluster) isRunning(id string) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, n := range c.nodes {
+		if n.IsHealthy() && n.Container(id) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the container with `id`, wherever it lives, healthy or not.
+func (c *Cluster) lookup(id string) *cluster.Container {
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, n := range c.nodes {
+		if container := n.Container(id); container != nil {
+			return container
+		}
+	}
+	return nil
+}
+
+// healthyNodes returns all the healthy nodes of the cluster.
+func (c *Cluster) healthyNodes() []cluster.Node {
+	c.RLock()
+	defer c.RUnlock()
+
+	out := []cluster.Node{}
+	for _, n := range c.nodes {
+		if n.IsHealthy() {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// reschedule re-creates the container described by `st` on a healthy node and
+// updates the store accordingly. The container keeps its cluster-wide ID.
+func (c *Cluster) reschedule(st *state.RequestedState) error {
+	old := c.lookup(st.Engine())
+
+	n, err := c.selectNode(c.healthyNodes(), st.Config)
+	if err != nil {
+		return err
+	}
+	nn, ok := n.(*node)
+	if !ok {
+		return fmt.Errorf("unexpected node %s", n.Name())
+	}
+
+	container, err := c.recreate(st, old, nn)
+	if err != nil {
+		return err
+	}
+
+	// The old container is gone with its node, stop reporting it and remove
+	// it if the node comes back.
+	if old != nil {
+		if on, ok := old.Node.(*node); ok {
+			on.removeContainer(old)
+			c.stale[old.Id] = on.ID()
+		}
+	}
+
+	log.WithFields(log.Fields{"name": nn.name, "id": st.ID, "old": st.Engine(), "new": container.Id}).Info("Container rescheduled")
+	c.Handle(&cluster.Event{
+		Event: dockerclient.Event{
+			Status: "container_rescheduled",
+			Id:     container.Id,
+			From:   "swarm",
+			Time:   time.Now().Unix(),
+		},
+		Node: nn,
+	})
+	return nil
+}
+
+// recreate creates the container described by `st` on `n` and points its
+// state to the new container. It is started unless `old` is known to be stopped.
+func (c *Cluster) recreate(st *state.RequestedState, old *cluster.Container, n *node) (*cluster.Container, error) {
+	container, err := n.create(st.Config, st.Name, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only leave the container stopped if we know it was not running.
+	if old == nil || strings.Contains(old.Status, "Up") {
+		if err := n.start(container, &st.Config.HostConfig); err != nil {
+			log.WithFields(log.Fields{"name": n.name, "id": container.Id}).Errorf("Unable to start re-created container: %v", err)
+		}
+	}
+
+	return withSwarmID(container, st.ID), c.replaceState(&state.RequestedState{
+		ID:       st.ID,
+		EngineID: container.Id,
+		Name:     st.Name,
+		Config:   st.Config,
+		Mode:     st.Mode,
+	})
+}
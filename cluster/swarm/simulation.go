@@ -0,0 +1,114 @@
+package swarm
+
+import (
+	"strings"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+)
+
+// A copy of a node whose containers can be added and removed without
+// touching the engine, to plan ahead what the scheduler would do.
+type simulatedNode struct {
+	*node
+
+	containers []*cluster.Container
+	usedCpus   int64
+	usedMemory int64
+}
+
+func newSimulatedNode(n *node) *simulatedNode {
+	return &simulatedNode{
+		node:       n,
+		containers: n.Containers(),
+		usedCpus:   n.UsedCpus(),
+		usedMemory: n.UsedMemory(),
+	}
+}
+
+// Return the simulated copies of `nodes`.
+func simulateNodes(nodes []cluster.Node) []*simulatedNode {
+	out := []*simulatedNode{}
+	for _, n := range nodes {
+		if nn, ok := n.(*node); ok {
+			out = append(out, newSimulatedNode(nn))
+		}
+	}
+	return out
+}
+
+func (n *simulatedNode) Containers() []*cluster.Container {
+	return n.containers
+}
+
+func (n *simulatedNode) Container(IdOrName string) *cluster.Container {
+	if len(IdOrName) == 0 {
+		return nil
+	}
+
+	for _, container := range n.containers {
+		if strings.HasPrefix(container.Id, IdOrName) {
+			return container
+		}
+		for _, name := range container.Names {
+			if name == IdOrName || name == "/"+IdOrName {
+				return container
+			}
+		}
+	}
+	return nil
+}
+
+func (n *simulatedNode) UsedCpus() int64 {
+	return n.usedCpus
+}
+
+func (n *simulatedNode) UsedMemory() int64 {
+	return n.usedMemory
+}
+
+// Return the highest share of the CPUs or memory of the node reserved.
+func (n *simulatedNode) ratio() float64 {
+	return reservedRatio(n.usedCpus, n.TotalCpus(), n.usedMemory, n.TotalMemory())
+}
+
+// Pretend a container with `config` was created on the node.
+func (n *simulatedNode) add(name string, config *dockerclient.ContainerConfig) *cluster.Container {
+	container := &cluster.Container{Node: n}
+	container.Id = name
+	container.Names = []string{"/" + name}
+	container.Info.Config = config
+	container.Info.HostConfig = &config.HostConfig
+	n.containers = append(n.containers, container)
+	n.usedCpus += config.CpuShares
+	n.usedMemory += config.Memory
+	return container
+}
+
+// Pretend `container` was removed from the node.
+func (n *simulatedNode) remove(container *cluster.Container) {
+	for i, c := range n.containers {
+		if c == container {
+			n.containers = append(n.containers[:i], n.containers[i+1:]...)
+			if container.Info.Config != nil {
+				n.usedCpus -= container.Info.Config.CpuShares
+				n.usedMemory -= container.Info.Config.Memory
+			}
+			return
+		}
+	}
+}
+
+// Return the highest of the CPUs and memory reservation ratios.
+func reservedRatio(usedCpus, totalCpus, usedMemory, totalMemory int64) float64 {
+	var ratio float64
+	if totalCpus > 0 {
+		ratio = float64(usedCpus) / float64(totalCpus)
+	}
+	if totalMemory > 0 {
+		if r := float64(usedMemory) / float64(totalMemory); r > ratio {
+			ratio = r
+		}
+	}
+	return ratio
+}
@@ -0,0 +1,110 @@
+package swarm
+
+import (
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler"
+	"github.com/samalba/dockerclient"
+)
+
+// Warmup entry standing for every image referenced by the requested state.
+const warmupState = "state"
+
+// Return whether the node is still pulling its warmup images.
+func (n *node) isWarming() bool {
+	n.RLock()
+	defer n.RUnlock()
+
+	return n.warming
+}
+
+func (n *node) setWarming(warming bool) {
+	n.Lock()
+	defer n.Unlock()
+
+	n.warming = warming
+}
+
+// Return the images to pull on the new nodes.
+func (c *Cluster) warmupImages() []string {
+	var (
+		images = []string{}
+		seen   = make(map[string]bool)
+	)
+	add := func(image string) {
+		if image != "" && !seen[image] {
+			seen[image] = true
+			images = append(images, image)
+		}
+	}
+
+	for _, image := range c.options.Warmup {
+		if image != warmupState {
+			add(image)
+			continue
+		}
+		for _, st := range c.store.All() {
+			if st.Config != nil {
+				add(st.Config.Image)
+			}
+		}
+	}
+	return images
+}
+
+// Pull the warmup images missing from a new node. The node is deprioritized
+// until done.
+func (c *Cluster) warmup(n *node) {
+	images := []string{}
+	for _, image := range c.warmupImages() {
+		if n.Image(image) == nil {
+			images = append(images, image)
+		}
+	}
+	if len(images) == 0 {
+		return
+	}
+
+	n.setWarming(true)
+	defer n.setWarming(false)
+
+	log.WithFields(log.Fields{"name": n.name, "images": images}).Info("Warming up node")
+	for _, image := range images {
+		if err := n.pull(image); err != nil {
+			log.WithFields(log.Fields{"name": n.name, "image": image}).Errorf("Unable to pull warmup image: %v", err)
+		}
+	}
+	n.refreshImages()
+	log.WithField("name", n.name).Info("Node warmed up")
+}
+
+// Select a node for `config` among `nodes`, preferring the ones done warming up.
+func (c *Cluster) selectNode(nodes []cluster.Node, config *dockerclient.ContainerConfig) (cluster.Node, error) {
+	n, _, err := c.schedule(nodes, config)
+	return n, err
+}
+
+// Like selectNode, also returning the trace of the decision.
+func (c *Cluster) schedule(nodes []cluster.Node, config *dockerclient.ContainerConfig) (cluster.Node, *cluster.Decision, error) {
+	return c.scheduleWith(c.scheduler, nodes, config)
+}
+
+// Like schedule, with the scheduler `s`.
+func (c *Cluster) scheduleWith(s *scheduler.Scheduler, nodes []cluster.Node, config *dockerclient.ContainerConfig) (cluster.Node, *cluster.Decision, error) {
+	ready := []cluster.Node{}
+	for _, n := range nodes {
+		if w, ok := n.(interface {
+			isWarming() bool
+		}); ok && w.isWarming() {
+			continue
+		}
+		ready = append(ready, n)
+	}
+
+	if len(ready) > 0 && len(ready) < len(nodes) {
+		if n, decision, err := s.Schedule(ready, config); err == nil {
+			return n, decision, nil
+		}
+	}
+	return s.Schedule(nodes, config)
+}
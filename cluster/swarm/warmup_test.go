@@ -0,0 +1,81 @@
+package swarm
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/strategy"
+	"github.com/docker/swarm/state"
+	"github.com/samalba/dockerclient"
+	"github.com/samalba/dockerclient/mockclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "warmup-test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := state.NewFileStore(dir)
+	assert.NoError(t, store.Initialize())
+	assert.NoError(t, store.Add("swarm-1", &state.RequestedState{ID: "swarm-1", Config: &dockerclient.ContainerConfig{Image: "redis"}}))
+	assert.NoError(t, store.Add("swarm-2", &state.RequestedState{ID: "swarm-2", Config: &dockerclient.ContainerConfig{Image: "busybox"}}))
+
+	c := &Cluster{
+		nodes:   make(map[string]*node),
+		options: &cluster.Options{Warmup: []string{"busybox", "state"}},
+		store:   store,
+	}
+
+	images := c.warmupImages()
+	assert.Equal(t, len(images), 2)
+	assert.Equal(t, images[0], "busybox")
+	assert.Equal(t, images[1], "redis")
+
+	// Only the missing images are pulled.
+	client := mockclient.NewMockClient()
+	client.On("PullImage", "redis", (*dockerclient.AuthConfig)(nil)).Return(nil).Once()
+	client.On("ListImages").Return([]*dockerclient.Image{{RepoTags: []string{"busybox"}}, {RepoTags: []string{"redis"}}}, nil)
+
+	n := createNode(t, "node-1")
+	n.client = client
+	n.images = []*cluster.Image{{Image: dockerclient.Image{RepoTags: []string{"busybox"}}, Node: n}}
+
+	c.warmup(n)
+	assert.False(t, n.isWarming())
+	assert.NotNil(t, n.Image("redis"))
+	client.Mock.AssertExpectations(t)
+}
+
+func TestSelectNodeWhileWarming(t *testing.T) {
+	// The spread strategy alone would choose the empty, warming, node.
+	c := &Cluster{
+		scheduler: scheduler.New(&strategy.SpreadPlacementStrategy{}, []filter.Filter{&filter.HealthFilter{}}),
+	}
+
+	warming := createNode(t, "warming")
+	warming.Cpus = 2
+	warming.warming = true
+	ready := createNode(t, "ready")
+	ready.Cpus = 2
+	ready.addContainer(&cluster.Container{
+		Container: dockerclient.Container{Id: "busy"},
+		Info:      dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{CpuShares: 1}},
+		Node:      ready,
+	})
+	config := &dockerclient.ContainerConfig{CpuShares: 1}
+
+	// The nodes done warming up come first.
+	n, err := c.selectNode([]cluster.Node{warming, ready}, config)
+	assert.NoError(t, err)
+	assert.Equal(t, n, ready)
+
+	// Warming nodes are still used when nothing else fits.
+	n, err = c.selectNode([]cluster.Node{warming, ready}, &dockerclient.ContainerConfig{CpuShares: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, n, warming)
+}
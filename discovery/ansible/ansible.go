@@ -63,6 +63,15 @@ func generate(pattern string) []string {
 	return result
 }
 
+func reservedVar(name string) bool {
+	for _, prefix := range []string{"ansible_", "docker_", "swarm_"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func readSection(data string, section string) ([]string, error) {
 	m := map[string][]string{}
 	lines := strings.Split(data, "\n")
@@ -82,14 +91,19 @@ func readSection(data string, section string) ([]string, error) {
 			log.Info(currentSection)
 			m[currentSection] = make([]string, 0)
 		} else {
-			tokens := strings.Split(line, " ")
+			tokens := strings.Fields(line)
 			port := "2375"
 
+			// The host vars other than the ansible_, docker_ and swarm_
+			// ones are the metadata of the node.
+			metadata := ""
 			if len(tokens) > 1 {
-				for _, t := range tokens {
+				for _, t := range tokens[1:] {
 					attr := strings.SplitN(t, "=", 2)
 					if attr[0] == "docker_port" {
 						port = attr[1]
+					} else if len(attr) == 2 && !reservedVar(attr[0]) {
+						metadata += " " + t
 					}
 				}
 			}
@@ -101,15 +115,15 @@ func readSection(data string, section string) ([]string, error) {
 					if strings.Contains(r, ":") == false {
 						r = r + ":" + port
 					}
-					m[currentSection] = append(m[currentSection], r)
-					all = append(all, r)
+					m[currentSection] = append(m[currentSection], r+metadata)
+					all = append(all, r+metadata)
 				}
 			} else {
 				if strings.Contains(tokens[0], ":") == false {
 					tokens[0] = tokens[0] + ":" + port
 				}
-				m[currentSection] = append(m[currentSection], tokens[0])
-				all = append(all, tokens[0])
+				m[currentSection] = append(m[currentSection], tokens[0]+metadata)
+				all = append(all, tokens[0]+metadata)
 			}
 		}
 	}
@@ -67,6 +67,20 @@ func TestReadSection(t *testing.T) {
 	assert.Equal(t, "192.168.0.4:2375", with_port[0])
 }
 
+func TestReadMetadata(t *testing.T) {
+	data := `
+[web]
+192.168.0.1 ansible_ssh_user=root docker_port=4243 zone=us-east weight=2
+192.168.0.[2:3] zone=us-west
+`
+	web, err := readSection(data, "web")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(web))
+	assert.Equal(t, "192.168.0.1:4243 zone=us-east weight=2", web[0])
+	assert.Equal(t, "192.168.0.2:2375 zone=us-west", web[1])
+	assert.Equal(t, "192.168.0.3:2375 zone=us-west", web[2])
+}
+
 func TestReadGenerator(t *testing.T) {
 	data := `
 [web]
@@ -2,6 +2,7 @@ package consul
 
 import (
 	"fmt"
+	"net/http"
 	"path"
 	"strings"
 	"time"
@@ -16,6 +17,7 @@ type ConsulDiscoveryService struct {
 	client    *consul.Client
 	prefix    string
 	lastIndex uint64
+	session   string
 }
 
 func init() {
@@ -23,6 +25,11 @@ func init() {
 }
 
 func (s *ConsulDiscoveryService) Initialize(uris string, heartbeat int) error {
+	uris, options, err := discovery.ParseOptions(uris)
+	if err != nil {
+		return err
+	}
+
 	parts := strings.SplitN(uris, "/", 2)
 	if len(parts) < 2 {
 		return fmt.Errorf("invalid format %q, missing <path>", uris)
@@ -32,6 +39,18 @@ func (s *ConsulDiscoveryService) Initialize(uris string, heartbeat int) error {
 
 	config := consul.DefaultConfig()
 	config.Address = addr
+	config.Token = options.Get("token")
+	if username := options.Get("username"); username != "" {
+		config.HttpAuth = &consul.HttpBasicAuth{Username: username, Password: options.Get("password")}
+	}
+	tlsConfig, err := options.TLSConfig()
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		config.Scheme = "https"
+		config.HttpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	}
 
 	client, err := consul.NewClient(config)
 	if err != nil {
@@ -107,3 +126,48 @@ func (s *ConsulDiscoveryService) waitForChange() <-chan uint64 {
 	}()
 	return c
 }
+
+// Lock holds `key` with a session expiring after `ttl`.
+func (s *ConsulDiscoveryService) Lock(key, value string, ttl time.Duration) (bool, error) {
+	session := s.client.Session()
+
+	// Renew our session, or create a new one if it expired.
+	if s.session != "" {
+		if entry, _, err := session.Renew(s.session, nil); err != nil || entry == nil {
+			s.session = ""
+		}
+	}
+	if s.session == "" {
+		id, _, err := session.Create(&consul.SessionEntry{TTL: ttl.String(), Behavior: consul.SessionBehaviorDelete}, nil)
+		if err != nil {
+			return false, err
+		}
+		s.session = id
+	}
+
+	acquired, _, err := s.client.KV().Acquire(&consul.KVPair{Key: key, Value: []byte(value), Session: s.session}, nil)
+	return acquired, err
+}
+
+func (s *ConsulDiscoveryService) Unlock(key, value string) error {
+	if s.session == "" {
+		return nil
+	}
+	if _, _, err := s.client.KV().Release(&consul.KVPair{Key: key, Value: []byte(value), Session: s.session}, nil); err != nil {
+		return err
+	}
+	_, err := s.client.Session().Destroy(s.session, nil)
+	s.session = ""
+	return err
+}
+
+func (s *ConsulDiscoveryService) Holder(key string) (string, error) {
+	pair, _, err := s.client.KV().Get(key, nil)
+	if err != nil {
+		return "", err
+	}
+	if pair == nil || pair.Session == "" {
+		return "", nil
+	}
+	return string(pair.Value), nil
+}
@@ -1,8 +1,21 @@
 package consul
 
 import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/docker/swarm/discovery"
+	consul "github.com/hashicorp/consul/api"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,4 +30,125 @@ func TestInitialize(t *testing.T) {
 	assert.Error(t, discovery.Initialize("127.0.0.1,127.0.0.2,127.0.0.3/path", 0))
 	assert.Equal(t, discovery.prefix, "path/")
 
+	assert.Error(t, discovery.Initialize("127.0.0.1/path?foo=bar", 0))
+}
+
+// An in-process consul agent serving the KV store, requiring `token`.
+type fakeConsul struct {
+	sync.Mutex
+	token   string
+	kv      map[string][]byte
+	index   uint64
+	changed chan struct{}
+}
+
+func newFakeConsul(token string) *fakeConsul {
+	return &fakeConsul{token: token, kv: make(map[string][]byte), index: 1, changed: make(chan struct{})}
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("token") != f.token {
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+
+	switch r.Method {
+	case "PUT":
+		value, _ := ioutil.ReadAll(r.Body)
+		f.Lock()
+		f.kv[key] = value
+		f.index++
+		close(f.changed)
+		f.changed = make(chan struct{})
+		f.Unlock()
+		w.Write([]byte("true"))
+	case "GET":
+		// Blocking queries wait for the index to move past the given one.
+		f.Lock()
+		if index, err := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); err == nil && index >= f.index {
+			wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+			changed := f.changed
+			f.Unlock()
+			select {
+			case <-changed:
+			case <-time.After(wait):
+			}
+			f.Lock()
+		}
+		defer f.Unlock()
+
+		_, recurse := r.URL.Query()["recurse"]
+		pairs := []*consul.KVPair{}
+		for k, v := range f.kv {
+			if k == key || (recurse && strings.HasPrefix(k, key)) {
+				pairs = append(pairs, &consul.KVPair{Key: k, Value: v})
+			}
+		}
+		sort.Sort(byKey(pairs))
+
+		w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+		w.Header().Set("X-Consul-LastContact", "0")
+		w.Header().Set("X-Consul-KnownLeader", "true")
+		if len(pairs) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(pairs)
+	}
+}
+
+type byKey []*consul.KVPair
+
+func (p byKey) Len() int           { return len(p) }
+func (p byKey) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byKey) Less(i, j int) bool { return p[i].Key < p[j].Key }
+
+// Write the certificate of `server` to a file, for the client to trust it.
+func writeCA(t *testing.T, server *httptest.Server) string {
+	file, err := ioutil.TempFile("", "consul-ca")
+	assert.NoError(t, err)
+	defer file.Close()
+	assert.NoError(t, pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]}))
+	return file.Name()
+}
+
+func TestFakeConsul(t *testing.T) {
+	server := httptest.NewTLSServer(newFakeConsul("secret"))
+	defer server.Close()
+	ca := writeCA(t, server)
+	defer os.Remove(ca)
+	addr := strings.TrimPrefix(server.URL, "https://")
+
+	// The token and the CA are both required.
+	d := &ConsulDiscoveryService{}
+	assert.Error(t, d.Initialize(addr+"/swarm?tlscacert="+ca, 1))
+	assert.Error(t, d.Initialize(addr+"/swarm?token=secret", 1))
+	assert.NoError(t, d.Initialize(addr+"/swarm?token=secret&tlscacert="+ca, 1))
+
+	assert.NoError(t, d.Register("1.1.1.1:1111"))
+	entries, err := d.Fetch()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, entries[0].String(), "1.1.1.1:1111")
+
+	// The watch sees the nodes joining.
+	watched := make(chan []*discovery.Entry, 10)
+	go d.Watch(func(entries []*discovery.Entry) { watched <- entries })
+	assert.NoError(t, d.Register("2.2.2.2:2222"))
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case entries := <-watched:
+			if len(entries) < 2 {
+				continue
+			}
+			assert.Equal(t, entries[0].String(), "1.1.1.1:1111")
+			assert.Equal(t, entries[1].String(), "2.2.2.2:2222")
+			return
+		case <-timeout:
+			t.Fatal("the watch never saw the second node")
+		}
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
@@ -12,6 +13,17 @@ import (
 type Entry struct {
 	Host string
 	Port string
+
+	// Labels of the node, on top of the ones of its engine.
+	Labels map[string]string
+
+	// Name to verify the TLS certificate of the node against, when it
+	// doesn't match its host.
+	TLSServerName string
+
+	// Share of the containers the node gets relative to the other nodes, 0
+	// when unset.
+	Weight int64
 }
 
 func NewEntry(url string) (*Entry, error) {
@@ -19,7 +31,44 @@ func NewEntry(url string) (*Entry, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Entry{host, port}, nil
+	return &Entry{Host: host, Port: port}, nil
+}
+
+// Parse an address followed by its metadata, such as
+// `10.0.0.1:2375 zone=us-east weight=2 tlsservername=node-1`. Every key other
+// than `weight` and `tlsservername` is a label.
+func ParseEntry(line string) (*Entry, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty entry")
+	}
+	entry, err := NewEntry(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields[1:] {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid metadata %q of %s, expected key=value", field, fields[0])
+		}
+		switch kv[0] {
+		case "weight":
+			weight, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil || weight <= 0 {
+				return nil, fmt.Errorf("invalid weight %q of %s, expected a positive integer", kv[1], fields[0])
+			}
+			entry.Weight = weight
+		case "tlsservername":
+			entry.TLSServerName = kv[1]
+		default:
+			if entry.Labels == nil {
+				entry.Labels = make(map[string]string)
+			}
+			entry.Labels[kv[0]] = kv[1]
+		}
+	}
+	return entry, nil
 }
 
 func (m Entry) String() string {
@@ -84,10 +133,10 @@ func CreateEntries(addrs []string) ([]*Entry, error) {
 	}
 
 	for _, addr := range addrs {
-		if len(addr) == 0 {
+		if len(strings.TrimSpace(addr)) == 0 {
 			continue
 		}
-		entry, err := NewEntry(addr)
+		entry, err := ParseEntry(addr)
 		if err != nil {
 			return nil, err
 		}
@@ -16,6 +16,28 @@ func TestNewEntry(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestParseEntry(t *testing.T) {
+	entry, err := ParseEntry("127.0.0.1:2375")
+	assert.NoError(t, err)
+	assert.Equal(t, entry.String(), "127.0.0.1:2375")
+	assert.Nil(t, entry.Labels)
+	assert.Equal(t, entry.Weight, 0)
+
+	entry, err = ParseEntry("127.0.0.1:2375  zone=us-east weight=2 tlsservername=node-1 empty=")
+	assert.NoError(t, err)
+	assert.Equal(t, entry.String(), "127.0.0.1:2375")
+	assert.Equal(t, entry.Labels, map[string]string{"zone": "us-east", "empty": ""})
+	assert.Equal(t, entry.Weight, 2)
+	assert.Equal(t, entry.TLSServerName, "node-1")
+
+	_, err = ParseEntry("127.0.0.1:2375 zone")
+	assert.Error(t, err)
+	_, err = ParseEntry("127.0.0.1:2375 weight=0")
+	assert.Error(t, err)
+	_, err = ParseEntry("127.0.0.1:2375 weight=heavy")
+	assert.Error(t, err)
+}
+
 func TestParse(t *testing.T) {
 	scheme, uri := parse("127.0.0.1:2375")
 	assert.Equal(t, scheme, "nodes")
@@ -51,4 +73,9 @@ func TestCreateEntries(t *testing.T) {
 
 	_, err = CreateEntries([]string{"127.0.0.1", "127.0.0.2"})
 	assert.Error(t, err)
+
+	entries, err = CreateEntries([]string{"127.0.0.1:2375 zone=us-east", " "})
+	assert.NoError(t, err)
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].Labels["zone"], "us-east")
 }
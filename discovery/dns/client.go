@@ -0,0 +1,293 @@
+package dns
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	typeA   uint16 = 1
+	typeSRV uint16 = 33
+	classIN uint16 = 1
+
+	// Timeout of a query to a name server.
+	queryTimeout = 5 * time.Second
+)
+
+var errTruncated = errors.New("truncated DNS response")
+
+// Resolves the records of the hosts, along with how long they can be cached.
+type Resolver interface {
+	LookupSRV(name string) ([]*net.SRV, time.Duration, error)
+	LookupA(name string) ([]net.IP, time.Duration, error)
+}
+
+// A resolver querying name servers directly, since the resolver of the
+// standard library doesn't expose the TTL of the records.
+type client struct {
+	servers []string
+}
+
+// Return a client of the name servers of /etc/resolv.conf.
+func newClient() *client {
+	servers := []string{}
+	if file, err := os.Open("/etc/resolv.conf"); err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				servers = append(servers, net.JoinHostPort(fields[1], "53"))
+			}
+		}
+	}
+	if len(servers) == 0 {
+		servers = []string{"127.0.0.1:53"}
+	}
+	return &client{servers: servers}
+}
+
+// A resource record of an answer.
+type record struct {
+	ttl uint32
+	// The data of the record, and the whole message for compressed names.
+	data, msg []byte
+}
+
+func (c *client) LookupSRV(name string) ([]*net.SRV, time.Duration, error) {
+	records, ttl, err := c.query(name, typeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	srvs := []*net.SRV{}
+	for _, r := range records {
+		if len(r.data) < 7 {
+			return nil, 0, fmt.Errorf("invalid SRV record for %s", name)
+		}
+		target, _, err := readName(r.msg, len(r.msg)-len(r.data)+6)
+		if err != nil {
+			return nil, 0, err
+		}
+		srvs = append(srvs, &net.SRV{
+			Target:   target,
+			Port:     binary.BigEndian.Uint16(r.data[4:6]),
+			Priority: binary.BigEndian.Uint16(r.data[0:2]),
+			Weight:   binary.BigEndian.Uint16(r.data[2:4]),
+		})
+	}
+	return srvs, ttl, nil
+}
+
+func (c *client) LookupA(name string) ([]net.IP, time.Duration, error) {
+	records, ttl, err := c.query(name, typeA)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ips := []net.IP{}
+	for _, r := range records {
+		if len(r.data) != net.IPv4len {
+			return nil, 0, fmt.Errorf("invalid A record for %s", name)
+		}
+		ips = append(ips, net.IP(r.data))
+	}
+	return ips, ttl, nil
+}
+
+// Ask the name servers in turn for the records of `name`, and return them
+// with the lowest of their TTLs.
+func (c *client) query(name string, qtype uint16) ([]record, time.Duration, error) {
+	query, id := newQuery(name, qtype)
+
+	var err error
+	for _, server := range c.servers {
+		var msg []byte
+		msg, err = exchange("udp", server, query)
+		if err == errTruncated {
+			msg, err = exchange("tcp", server, query)
+		}
+		if err != nil {
+			continue
+		}
+
+		var records []record
+		records, err = parseAnswers(msg, id, qtype)
+		if err != nil {
+			continue
+		}
+		if len(records) == 0 {
+			return nil, 0, fmt.Errorf("no %s record found for %s", typeName(qtype), name)
+		}
+
+		ttl := records[0].ttl
+		for _, r := range records {
+			if r.ttl < ttl {
+				ttl = r.ttl
+			}
+		}
+		return records, time.Duration(ttl) * time.Second, nil
+	}
+	return nil, 0, fmt.Errorf("unable to resolve %s: %v", name, err)
+}
+
+func typeName(qtype uint16) string {
+	if qtype == typeSRV {
+		return "SRV"
+	}
+	return "A"
+}
+
+// Build a recursive query for the records of `name`.
+func newQuery(name string, qtype uint16) ([]byte, uint16) {
+	id := uint16(rand.Intn(1 << 16))
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[0:2], id)
+	binary.BigEndian.PutUint16(msg[2:4], 1<<8) // recursion desired
+	binary.BigEndian.PutUint16(msg[4:6], 1)    // one question
+
+	msg = appendName(msg, name)
+	msg = append(msg, byte(qtype>>8), byte(qtype), byte(classIN>>8), byte(classIN))
+	return msg, id
+}
+
+func appendName(msg []byte, name string) []byte {
+	for _, label := range strings.Split(strings.Trim(name, "."), ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	return append(msg, 0)
+}
+
+// Send `query` to `server` and return its response.
+func exchange(network, server string, query []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(network, server, queryTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(queryTimeout))
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		msg := make([]byte, 65535)
+		n, err := conn.Read(msg)
+		if err != nil {
+			return nil, err
+		}
+		if n >= 3 && msg[2]&0x02 != 0 {
+			return nil, errTruncated
+		}
+		return msg[:n], nil
+	}
+
+	// Over TCP, messages are prefixed by their length.
+	if _, err := conn.Write(append([]byte{byte(len(query) >> 8), byte(len(query))}, query...)); err != nil {
+		return nil, err
+	}
+	length := make([]byte, 2)
+	if _, err := io.ReadFull(conn, length); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(length))
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Return the records of type `qtype` answering the query `id`.
+func parseAnswers(msg []byte, id, qtype uint16) ([]record, error) {
+	if len(msg) < 12 {
+		return nil, errors.New("short DNS response")
+	}
+	if binary.BigEndian.Uint16(msg[0:2]) != id {
+		return nil, errors.New("mismatched DNS response")
+	}
+	switch rcode := msg[3] & 0x0f; rcode {
+	case 0:
+	case 3:
+		return nil, nil // no such name
+	default:
+		return nil, fmt.Errorf("DNS server failure, rcode %d", rcode)
+	}
+
+	questions := int(binary.BigEndian.Uint16(msg[4:6]))
+	answers := int(binary.BigEndian.Uint16(msg[6:8]))
+	offset := 12
+	for i := 0; i < questions; i++ {
+		_, next, err := readName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = next + 4
+	}
+
+	records := []record{}
+	for i := 0; i < answers; i++ {
+		_, next, err := readName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		if next+10 > len(msg) {
+			return nil, errors.New("short DNS record")
+		}
+		rtype := binary.BigEndian.Uint16(msg[next : next+2])
+		ttl := binary.BigEndian.Uint32(msg[next+4 : next+8])
+		length := int(binary.BigEndian.Uint16(msg[next+8 : next+10]))
+		offset = next + 10 + length
+		if offset > len(msg) {
+			return nil, errors.New("short DNS record")
+		}
+		// Skip the CNAMEs leading to the records.
+		if rtype == qtype {
+			records = append(records, record{ttl: ttl, data: msg[next+10 : offset], msg: msg[:offset]})
+		}
+	}
+	return records, nil
+}
+
+// Read the name at `offset` of `msg`, following the compression pointers, and
+// return it with the offset right after it.
+func readName(msg []byte, offset int) (string, int, error) {
+	labels := []string{}
+	next := -1
+	for jumps := 0; ; {
+		if offset >= len(msg) {
+			return "", 0, errors.New("short DNS name")
+		}
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case length&0xc0 == 0xc0:
+			if offset+1 >= len(msg) || jumps > 10 {
+				return "", 0, errors.New("invalid DNS name")
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:offset+2]) & 0x3fff)
+			jumps++
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, errors.New("short DNS name")
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
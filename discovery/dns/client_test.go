@@ -0,0 +1,49 @@
+package dns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadName(t *testing.T) {
+	msg := appendName([]byte{0, 0}, "example.internal")
+	name, next, err := readName(msg, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, name, "example.internal")
+	assert.Equal(t, next, len(msg))
+
+	// A label followed by a pointer to the name above.
+	compressed := append(msg, 4, 'n', 'o', 'd', 'e', 0xc0, 2)
+	name, next, err = readName(compressed, len(msg))
+	assert.NoError(t, err)
+	assert.Equal(t, name, "node.example.internal")
+	assert.Equal(t, next, len(compressed))
+
+	// Pointer loops and short names are rejected.
+	_, _, err = readName([]byte{0xc0, 0}, 0)
+	assert.Error(t, err)
+	_, _, err = readName([]byte{4, 'n', 'o'}, 0)
+	assert.Error(t, err)
+}
+
+func TestParseAnswers(t *testing.T) {
+	query, id := newQuery("example.internal", typeA)
+
+	_, err := parseAnswers(query[:8], id, typeA)
+	assert.Error(t, err)
+	_, err = parseAnswers(query, id+1, typeA)
+	assert.Error(t, err)
+
+	// Server failure.
+	failure := append([]byte{}, query...)
+	failure[3] = 2
+	_, err = parseAnswers(failure, id, typeA)
+	assert.Error(t, err)
+
+	// Unknown name.
+	failure[3] = 3
+	records, err := parseAnswers(failure, id, typeA)
+	assert.NoError(t, err)
+	assert.Len(t, records, 0)
+}
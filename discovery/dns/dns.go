@@ -0,0 +1,126 @@
+package dns
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/discovery"
+)
+
+const defaultPort = "2375"
+
+// DNSDiscoveryService finds the nodes in the DNS: in the SRV records of
+// `_docker._tcp.example.internal`, or in the A records of
+// `nodes.example.internal[:port]`. The name can be preceded by the name server
+// to ask, as in `10.0.0.2:53/_docker._tcp.example.internal`.
+type DNSDiscoveryService struct {
+	heartbeat int
+	name      string
+	port      string
+	srv       bool
+	resolver  Resolver
+
+	// TTL of the records last resolved.
+	ttl time.Duration
+}
+
+func init() {
+	discovery.Register("dns", &DNSDiscoveryService{})
+}
+
+func (s *DNSDiscoveryService) Initialize(uri string, heartbeat int) error {
+	c := newClient()
+	name := uri
+	if parts := strings.SplitN(uri, "/", 2); len(parts) == 2 {
+		server := parts[0]
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		c.servers = []string{server}
+		name = parts[1]
+	}
+	if name == "" {
+		return fmt.Errorf("invalid format %q, missing <name>", uri)
+	}
+
+	s.name, s.port, s.srv = name, "", strings.HasPrefix(name, "_")
+	if !s.srv {
+		s.port = defaultPort
+		if host, port, err := net.SplitHostPort(name); err == nil {
+			s.name, s.port = host, port
+		}
+	}
+	s.heartbeat = heartbeat
+	s.resolver = c
+	return nil
+}
+
+// Resolve the entries, and how long they can be cached.
+func (s *DNSDiscoveryService) resolve() ([]*discovery.Entry, time.Duration, error) {
+	addrs := []string{}
+
+	if s.srv {
+		srvs, ttl, err := s.resolver.LookupSRV(s.name)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, srv := range srvs {
+			addrs = append(addrs, net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))))
+		}
+		sort.Strings(addrs)
+		entries, err := discovery.CreateEntries(addrs)
+		return entries, ttl, err
+	}
+
+	ips, ttl, err := s.resolver.LookupA(s.name)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, ip := range ips {
+		addrs = append(addrs, net.JoinHostPort(ip.String(), s.port))
+	}
+	sort.Strings(addrs)
+	entries, err := discovery.CreateEntries(addrs)
+	return entries, ttl, err
+}
+
+func (s *DNSDiscoveryService) Fetch() ([]*discovery.Entry, error) {
+	entries, ttl, err := s.resolve()
+	if err == nil {
+		s.ttl = ttl
+	}
+	return entries, err
+}
+
+// Return how long to wait before resolving the records again: until they
+// expire, but no less than the heartbeat.
+func (s *DNSDiscoveryService) wait() time.Duration {
+	heartbeat := time.Duration(s.heartbeat) * time.Second
+	if s.ttl > heartbeat {
+		return s.ttl
+	}
+	return heartbeat
+}
+
+func (s *DNSDiscoveryService) Watch(callback discovery.WatchCallback) {
+	for {
+		time.Sleep(s.wait())
+
+		entries, err := s.Fetch()
+		if err != nil {
+			log.WithField("name", "dns").Errorf("Discovery failed: %v", err)
+			s.ttl = 0
+			continue
+		}
+		callback(entries)
+	}
+}
+
+func (s *DNSDiscoveryService) Register(addr string) error {
+	return discovery.ErrNotImplemented
+}
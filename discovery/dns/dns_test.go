@@ -0,0 +1,213 @@
+package dns
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/docker/swarm/discovery"
+	"github.com/stretchr/testify/assert"
+)
+
+// A record served by the stub DNS server.
+type stubRecord struct {
+	rtype uint16
+	ttl   uint32
+	data  []byte
+}
+
+func srvData(port uint16, target string) []byte {
+	data := make([]byte, 6)
+	binary.BigEndian.PutUint16(data[4:6], port)
+	return appendName(data, target)
+}
+
+// A local DNS server answering over UDP from `records`, by name. Responses
+// with more than `truncate` records are truncated over UDP, as real servers
+// do past 512 bytes.
+func stubServer(t *testing.T, records map[string][]stubRecord, truncate int) (string, func()) {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+	assert.NoError(t, err)
+
+	answer := func(query []byte, overUDP bool) []byte {
+		name, next, err := readName(query, 12)
+		assert.NoError(t, err)
+		qtype := binary.BigEndian.Uint16(query[next : next+2])
+
+		answers := []stubRecord{}
+		for _, r := range records[name] {
+			if r.rtype == qtype {
+				answers = append(answers, r)
+			}
+		}
+
+		msg := make([]byte, 12)
+		copy(msg, query[:2])
+		flags := uint16(0x8180)
+		if _, exists := records[name]; !exists {
+			flags |= 3
+		}
+		if overUDP && len(answers) > truncate {
+			flags |= 0x0200
+			answers = nil
+		}
+		binary.BigEndian.PutUint16(msg[2:4], flags)
+		binary.BigEndian.PutUint16(msg[4:6], 1)
+		binary.BigEndian.PutUint16(msg[6:8], uint16(len(answers)))
+		msg = append(msg, query[12:next+4]...)
+		for _, r := range answers {
+			// The name points to the one of the question.
+			rr := []byte{0xc0, 12, byte(r.rtype >> 8), byte(r.rtype), 0, 1, 0, 0, 0, 0, 0, 0}
+			binary.BigEndian.PutUint32(rr[6:10], r.ttl)
+			binary.BigEndian.PutUint16(rr[10:12], uint16(len(r.data)))
+			msg = append(append(msg, rr...), r.data...)
+		}
+		return msg
+	}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			udp.WriteTo(answer(buf[:n], true), addr)
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			length := make([]byte, 2)
+			conn.Read(length)
+			query := make([]byte, binary.BigEndian.Uint16(length))
+			conn.Read(query)
+			msg := answer(query, false)
+			conn.Write(append([]byte{byte(len(msg) >> 8), byte(len(msg))}, msg...))
+			conn.Close()
+		}
+	}()
+
+	return udp.LocalAddr().String(), func() {
+		udp.Close()
+		tcp.Close()
+	}
+}
+
+var stubRecords = map[string][]stubRecord{
+	"_docker._tcp.example.internal": {
+		{rtype: typeSRV, ttl: 60, data: srvData(2375, "node-2.example.internal.")},
+		{rtype: typeSRV, ttl: 30, data: srvData(2376, "node-1.example.internal.")},
+	},
+	"nodes.example.internal": {
+		{rtype: typeA, ttl: 10, data: []byte{10, 0, 0, 2}},
+		{rtype: typeA, ttl: 20, data: []byte{10, 0, 0, 1}},
+	},
+}
+
+func TestInitialize(t *testing.T) {
+	d := &DNSDiscoveryService{}
+	assert.NoError(t, d.Initialize("_docker._tcp.example.internal", 10))
+	assert.Equal(t, d.name, "_docker._tcp.example.internal")
+	assert.True(t, d.srv)
+
+	assert.NoError(t, d.Initialize("nodes.example.internal", 10))
+	assert.Equal(t, d.name, "nodes.example.internal")
+	assert.Equal(t, d.port, "2375")
+	assert.False(t, d.srv)
+
+	assert.NoError(t, d.Initialize("10.0.0.2/nodes.example.internal:4243", 10))
+	assert.Equal(t, d.name, "nodes.example.internal")
+	assert.Equal(t, d.port, "4243")
+	assert.Equal(t, d.resolver.(*client).servers, []string{"10.0.0.2:53"})
+
+	assert.Error(t, d.Initialize("10.0.0.2:53/", 10))
+}
+
+func TestFetch(t *testing.T) {
+	addr, stop := stubServer(t, stubRecords, 10)
+	defer stop()
+
+	d := &DNSDiscoveryService{}
+	assert.NoError(t, d.Initialize(addr+"/_docker._tcp.example.internal", 10))
+	entries, err := d.Fetch()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, entries[0].String(), "node-1.example.internal:2376")
+	assert.Equal(t, entries[1].String(), "node-2.example.internal:2375")
+	assert.Equal(t, d.ttl, 30*time.Second)
+
+	assert.NoError(t, d.Initialize(addr+"/nodes.example.internal", 10))
+	entries, err = d.Fetch()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, entries[0].String(), "10.0.0.1:2375")
+	assert.Equal(t, entries[1].String(), "10.0.0.2:2375")
+	assert.Equal(t, d.ttl, 10*time.Second)
+
+	assert.NoError(t, d.Initialize(addr+"/unknown.example.internal", 10))
+	_, err = d.Fetch()
+	assert.Error(t, err)
+}
+
+func TestFetchTruncated(t *testing.T) {
+	// Truncated responses are asked again over TCP.
+	addr, stop := stubServer(t, stubRecords, 1)
+	defer stop()
+
+	d := &DNSDiscoveryService{}
+	assert.NoError(t, d.Initialize(addr+"/_docker._tcp.example.internal", 10))
+	entries, err := d.Fetch()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestWait(t *testing.T) {
+	d := &DNSDiscoveryService{heartbeat: 10}
+	assert.Equal(t, d.wait(), 10*time.Second)
+	d.ttl = 5 * time.Second
+	assert.Equal(t, d.wait(), 10*time.Second)
+	d.ttl = time.Minute
+	assert.Equal(t, d.wait(), time.Minute)
+}
+
+// A resolver whose records change on demand.
+type stubResolver struct {
+	ips chan []net.IP
+}
+
+func (r *stubResolver) LookupSRV(name string) ([]*net.SRV, time.Duration, error) {
+	return nil, 0, nil
+}
+
+func (r *stubResolver) LookupA(name string) ([]net.IP, time.Duration, error) {
+	return <-r.ips, 0, nil
+}
+
+func TestWatch(t *testing.T) {
+	resolver := &stubResolver{ips: make(chan []net.IP, 1)}
+	d := &DNSDiscoveryService{name: "nodes.example.internal", port: "2375", resolver: resolver}
+
+	watched := make(chan []*discovery.Entry)
+	go d.Watch(func(entries []*discovery.Entry) { watched <- entries })
+
+	resolver.ips <- []net.IP{net.ParseIP("10.0.0.1")}
+	entries := <-watched
+	assert.Len(t, entries, 1)
+	assert.Equal(t, entries[0].String(), "10.0.0.1:2375")
+
+	resolver.ips <- []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}
+	entries = <-watched
+	assert.Len(t, entries, 2)
+}
+
+func TestRegister(t *testing.T) {
+	d := &DNSDiscoveryService{}
+	assert.Error(t, d.Register("0.0.0.0"))
+}
@@ -2,8 +2,11 @@ package etcd
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/coreos/go-etcd/etcd"
@@ -21,6 +24,11 @@ func init() {
 }
 
 func (s *EtcdDiscoveryService) Initialize(uris string, heartbeat int) error {
+	uris, options, err := discovery.ParseOptions(uris)
+	if err != nil {
+		return err
+	}
+
 	var (
 		// split here because uris can contain multiples ips
 		// like `etcd://192.168.0.1,192.168.0.2,192.168.0.3/path`
@@ -33,11 +41,29 @@ func (s *EtcdDiscoveryService) Initialize(uris string, heartbeat int) error {
 		return fmt.Errorf("invalid format %q, missing <path>", uris)
 	}
 
+	tlsConfig, err := options.TLSConfig()
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+
+	// The credentials are sent along with every request as basic auth.
+	var user *url.Userinfo
+	if username := options.Get("username"); username != "" {
+		user = url.UserPassword(username, options.Get("password"))
+	}
+
 	for _, ip := range ips {
-		entries = append(entries, "http://"+ip)
+		entries = append(entries, (&url.URL{Scheme: scheme, User: user, Host: ip}).String())
 	}
 
 	s.client = etcd.NewClient(entries)
+	if tlsConfig != nil {
+		s.client.SetTransport(&http.Transport{TLSClientConfig: tlsConfig})
+	}
 	s.ttl = uint64(heartbeat * 3 / 2)
 	s.path = "/" + parts[1] + "/"
 	if _, err := s.client.CreateDir(s.path, s.ttl); err != nil {
@@ -80,3 +106,46 @@ func (s *EtcdDiscoveryService) Register(addr string) error {
 	_, err := s.client.Set(path.Join(s.path, addr), addr, s.ttl)
 	return err
 }
+
+// Lock creates `key` with a TTL, or refreshes it if we already hold it.
+func (s *EtcdDiscoveryService) Lock(key, value string, ttl time.Duration) (bool, error) {
+	seconds := uint64(ttl.Seconds())
+
+	_, err := s.client.Create(key, value, seconds)
+	if err == nil {
+		return true, nil
+	}
+	if etcdError, ok := err.(*etcd.EtcdError); !ok || etcdError.ErrorCode != 105 { // key already exists
+		return false, err
+	}
+
+	// Refresh the TTL, unless somebody else holds the lock.
+	if _, err := s.client.CompareAndSwap(key, value, seconds, value, 0); err != nil {
+		if etcdError, ok := err.(*etcd.EtcdError); ok && (etcdError.ErrorCode == 100 || etcdError.ErrorCode == 101) { // key not found, compare failed
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *EtcdDiscoveryService) Unlock(key, value string) error {
+	if _, err := s.client.CompareAndDelete(key, value, 0); err != nil {
+		if etcdError, ok := err.(*etcd.EtcdError); ok && (etcdError.ErrorCode == 100 || etcdError.ErrorCode == 101) { // key not found, compare failed
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *EtcdDiscoveryService) Holder(key string) (string, error) {
+	resp, err := s.client.Get(key, false, false)
+	if err != nil {
+		if etcdError, ok := err.(*etcd.EtcdError); ok && etcdError.ErrorCode == 100 { // key not found
+			return "", nil
+		}
+		return "", err
+	}
+	return resp.Node.Value, nil
+}
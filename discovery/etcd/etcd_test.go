@@ -1,8 +1,21 @@
 package etcd
 
 import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/coreos/go-etcd/etcd"
+	"github.com/docker/swarm/discovery"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,4 +29,147 @@ func TestInitialize(t *testing.T) {
 
 	assert.Error(t, discovery.Initialize("127.0.0.1,127.0.0.2,127.0.0.3/path", 0))
 	assert.Equal(t, discovery.path, "/path/")
+
+	assert.Error(t, discovery.Initialize("127.0.0.1/path?foo=bar", 0))
+}
+
+// An in-process etcd v2 server, requiring basic auth as `username`.
+type fakeEtcd struct {
+	sync.Mutex
+	username, password string
+	dirs               map[string]bool
+	kv                 map[string]string
+	index              uint64
+	changed            chan struct{}
+}
+
+func newFakeEtcd(username, password string) *fakeEtcd {
+	return &fakeEtcd{
+		username: username,
+		password: password,
+		dirs:     make(map[string]bool),
+		kv:       make(map[string]string),
+		index:    1,
+		changed:  make(chan struct{}),
+	}
+}
+
+func (f *fakeEtcd) reply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Etcd-Index", strconv.FormatUint(f.index, 10))
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (f *fakeEtcd) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if username, password, ok := r.BasicAuth(); !ok || username != f.username || password != f.password {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	r.ParseForm()
+	key := "/" + strings.Trim(strings.TrimPrefix(r.URL.Path, "/v2/keys"), "/")
+
+	switch r.Method {
+	case "PUT":
+		f.Lock()
+		defer f.Unlock()
+		if _, exists := f.kv[key]; (exists || f.dirs[key]) && r.FormValue("prevExist") == "false" {
+			f.reply(w, http.StatusPreconditionFailed, &etcd.EtcdError{ErrorCode: 105, Message: "Key already exists", Cause: key, Index: f.index})
+			return
+		}
+		if r.FormValue("dir") == "true" {
+			f.dirs[key] = true
+		} else {
+			f.kv[key] = r.FormValue("value")
+		}
+		f.index++
+		close(f.changed)
+		f.changed = make(chan struct{})
+		f.reply(w, http.StatusCreated, &etcd.Response{Action: "set", Node: &etcd.Node{Key: key, Value: f.kv[key], ModifiedIndex: f.index}})
+	case "GET":
+		f.Lock()
+		defer f.Unlock()
+		if r.FormValue("wait") == "true" {
+			// Watches wait for the index to reach the given one, for a
+			// second at most so that the server can be closed.
+			waitIndex, _ := strconv.ParseUint(r.FormValue("waitIndex"), 10, 64)
+			if waitIndex == 0 || f.index < waitIndex {
+				changed := f.changed
+				f.Unlock()
+				select {
+				case <-changed:
+				case <-time.After(time.Second):
+				}
+				f.Lock()
+			}
+			f.reply(w, http.StatusOK, &etcd.Response{Action: "set", Node: &etcd.Node{Key: key, ModifiedIndex: f.index}})
+			return
+		}
+
+		if !f.dirs[key] {
+			f.reply(w, http.StatusNotFound, &etcd.EtcdError{ErrorCode: 100, Message: "Key not found", Cause: key, Index: f.index})
+			return
+		}
+		node := &etcd.Node{Key: key, Dir: true}
+		for k, v := range f.kv {
+			if strings.HasPrefix(k, key+"/") {
+				node.Nodes = append(node.Nodes, &etcd.Node{Key: k, Value: v})
+			}
+		}
+		sort.Sort(node.Nodes)
+		f.reply(w, http.StatusOK, &etcd.Response{Action: "get", Node: node})
+	}
+}
+
+// Write the certificate of `server` to a file, for the client to trust it.
+func writeCA(t *testing.T, server *httptest.Server) string {
+	file, err := ioutil.TempFile("", "etcd-ca")
+	assert.NoError(t, err)
+	defer file.Close()
+	assert.NoError(t, pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: server.TLS.Certificates[0].Certificate[0]}))
+	return file.Name()
+}
+
+func TestFakeEtcd(t *testing.T) {
+	server := httptest.NewTLSServer(newFakeEtcd("swarm", "secret"))
+	defer server.Close()
+	ca := writeCA(t, server)
+	defer os.Remove(ca)
+	addr := strings.TrimPrefix(server.URL, "https://")
+
+	// The credentials and the CA are both required.
+	d := &EtcdDiscoveryService{}
+	assert.Error(t, d.Initialize(addr+"/swarm?tlscacert="+ca, 1))
+	assert.Error(t, d.Initialize(addr+"/swarm?username=swarm&password=wrong&tlscacert="+ca, 1))
+	assert.Error(t, d.Initialize(addr+"/swarm?username=swarm&password=secret", 1))
+	assert.NoError(t, d.Initialize(addr+"/swarm?username=swarm&password=secret&tlscacert="+ca, 1))
+	// The directory already exists the second time.
+	assert.NoError(t, d.Initialize(addr+"/swarm?username=swarm&password=secret&tlscacert="+ca, 1))
+
+	assert.NoError(t, d.Register("1.1.1.1:1111"))
+	entries, err := d.Fetch()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, entries[0].String(), "1.1.1.1:1111")
+
+	// The watch sees the nodes joining.
+	watched := make(chan []*discovery.Entry, 10)
+	go d.Watch(func(entries []*discovery.Entry) { watched <- entries })
+
+	timeout := time.After(5 * time.Second)
+	for {
+		assert.NoError(t, d.Register("2.2.2.2:2222"))
+		select {
+		case entries := <-watched:
+			if len(entries) < 2 {
+				continue
+			}
+			assert.Equal(t, entries[0].String(), "1.1.1.1:1111")
+			assert.Equal(t, entries[1].String(), "2.2.2.2:2222")
+			return
+		case <-time.After(100 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("the watch never saw the second node")
+		}
+	}
 }
@@ -2,15 +2,26 @@ package file
 
 import (
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"time"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/docker/swarm/discovery"
 )
 
+// How long the file must stay still after a change before it's read again,
+// so that a file being written is read once, when complete.
+const debounceDelay = 200 * time.Millisecond
+
+// FileDiscoveryService reads the nodes from a file, or from the `*.conf`
+// files of a directory.
 type FileDiscoveryService struct {
 	heartbeat int
 	path      string
+	isDir     bool
 }
 
 func init() {
@@ -20,32 +31,145 @@ func init() {
 func (s *FileDiscoveryService) Initialize(path string, heartbeat int) error {
 	s.path = path
 	s.heartbeat = heartbeat
+	if info, err := os.Stat(path); err == nil {
+		s.isDir = info.IsDir()
+	}
 	return nil
 }
 
-func parseFileContent(content []byte) []string {
-	result := make([]string, 0)
-	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
-		for _, ip := range discovery.Generate(line) {
-			result = append(result, ip)
-		}
+// Every line holds an address, or a range of them, optionally followed by the
+// metadata of the nodes: `10.0.0.[1:3]:2375 zone=us-east weight=2`. Anything
+// after a `#` is a comment.
+func parseLine(line string) []string {
+	if i := strings.Index(line, "#"); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	result := []string{}
+	for _, ip := range discovery.Generate(fields[0]) {
+		result = append(result, strings.Join(append([]string{ip}, fields[1:]...), " "))
 	}
 	return result
 }
 
+// Return the entries of the file `name`. The malformed lines are logged and
+// skipped, rather than dropping every node of the file.
+func parseEntries(name string, content []byte) []*discovery.Entry {
+	entries := []*discovery.Entry{}
+	for i, line := range strings.Split(string(content), "\n") {
+		for _, addr := range parseLine(line) {
+			entry, err := discovery.ParseEntry(addr)
+			if err != nil {
+				log.WithField("name", "file").Errorf("Ignoring malformed line %s:%d %q: %v", name, i+1, strings.TrimSpace(line), err)
+				break
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Return the files listing the nodes.
+func (s *FileDiscoveryService) files() ([]string, error) {
+	if !s.isDir {
+		return []string{s.path}, nil
+	}
+	return filepath.Glob(filepath.Join(s.path, "*.conf"))
+}
+
 func (s *FileDiscoveryService) Fetch() ([]*discovery.Entry, error) {
-	fileContent, err := ioutil.ReadFile(s.path)
+	files, err := s.files()
 	if err != nil {
 		return nil, err
 	}
-	return discovery.CreateEntries(parseFileContent(fileContent))
+
+	entries := []*discovery.Entry{}
+	for _, file := range files {
+		fileContent, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, parseEntries(file, fileContent)...)
+	}
+	return entries, nil
 }
 
+// Return whether a change of the file `name` of the watched directory may
+// change the nodes.
+func (s *FileDiscoveryService) relevant(name string) bool {
+	if s.isDir {
+		return strings.HasSuffix(name, ".conf")
+	}
+	return name == filepath.Base(s.path)
+}
+
+// Send the changes of the nodes files: from inotify when supported, or every
+// heartbeat otherwise.
+func (s *FileDiscoveryService) changes() <-chan string {
+	dir := s.path
+	if !s.isDir {
+		dir = filepath.Dir(s.path)
+	}
+	changes, err := watchDir(dir)
+	if err == nil {
+		return changes
+	}
+	log.WithField("name", "file").Warnf("Unable to watch %s, polling it every %ds instead: %v", dir, s.heartbeat, err)
+
+	ticks := make(chan string)
+	go func() {
+		for _ = range time.Tick(time.Duration(s.heartbeat) * time.Second) {
+			ticks <- ""
+		}
+	}()
+	return ticks
+}
+
+// Watch calls back once the nodes files changed and stayed still for the
+// debounce delay, and only when the nodes actually changed.
 func (s *FileDiscoveryService) Watch(callback discovery.WatchCallback) {
-	for _ = range time.Tick(time.Duration(s.heartbeat) * time.Second) {
-		entries, err := s.Fetch()
-		if err == nil {
-			callback(entries)
+	last, _ := s.Fetch()
+
+	for {
+		changes := s.changes()
+		for name := range changes {
+			if name != "" && !s.relevant(name) {
+				continue
+			}
+			s.debounce(changes)
+
+			entries, err := s.Fetch()
+			if err != nil {
+				log.WithField("name", "file").Errorf("Discovery failed: %v", err)
+				continue
+			}
+			if !reflect.DeepEqual(entries, last) {
+				last = entries
+				callback(entries)
+			}
+		}
+
+		log.WithField("name", "file").Warnf("Lost the watch of %s, watching it again", s.path)
+		time.Sleep(time.Duration(s.heartbeat) * time.Second)
+	}
+}
+
+// Wait until `changes` stayed quiet for the debounce delay, or got closed.
+func (s *FileDiscoveryService) debounce(changes <-chan string) {
+	timer := time.After(debounceDelay)
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+			timer = time.After(debounceDelay)
+		case <-timer:
+			return
 		}
 	}
 }
@@ -1,8 +1,13 @@
 package file
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/docker/swarm/discovery"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,12 +22,164 @@ func TestContent(t *testing.T) {
 1.1.1.[1:2]:1111
 2.2.2.[2:4]:2222
 `
-	ips := parseFileContent([]byte(data))
-	assert.Equal(t, ips[0], "1.1.1.1:1111")
-	assert.Equal(t, ips[1], "1.1.1.2:1111")
-	assert.Equal(t, ips[2], "2.2.2.2:2222")
-	assert.Equal(t, ips[3], "2.2.2.3:2222")
-	assert.Equal(t, ips[4], "2.2.2.4:2222")
+	entries := parseEntries("cluster", []byte(data))
+	assert.Equal(t, len(entries), 5)
+	assert.Equal(t, entries[0].String(), "1.1.1.1:1111")
+	assert.Equal(t, entries[1].String(), "1.1.1.2:1111")
+	assert.Equal(t, entries[2].String(), "2.2.2.2:2222")
+	assert.Equal(t, entries[3].String(), "2.2.2.3:2222")
+	assert.Equal(t, entries[4].String(), "2.2.2.4:2222")
+}
+
+func TestContentMetadata(t *testing.T) {
+	data := `
+1.1.1.[1:2]:1111 zone=us-east weight=2
+
+2.2.2.2:2222
+`
+	entries := parseEntries("cluster", []byte(data))
+	assert.Equal(t, len(entries), 3)
+	assert.Equal(t, entries[0].String(), "1.1.1.1:1111")
+	assert.Equal(t, entries[0].Labels["zone"], "us-east")
+	assert.Equal(t, entries[0].Weight, int64(2))
+	assert.Equal(t, entries[1].String(), "1.1.1.2:1111")
+	assert.Equal(t, entries[1].Labels["zone"], "us-east")
+	assert.Equal(t, entries[2].String(), "2.2.2.2:2222")
+	assert.Equal(t, len(entries[2].Labels), 0)
+}
+
+func TestContentComments(t *testing.T) {
+	data := `
+# The web nodes.
+1.1.1.1:1111 # the first one
+  # indented comment
+2.2.2.2:2222
+`
+	entries := parseEntries("cluster", []byte(data))
+	assert.Equal(t, len(entries), 2)
+	assert.Equal(t, entries[0].String(), "1.1.1.1:1111")
+	assert.Equal(t, entries[1].String(), "2.2.2.2:2222")
+}
+
+func TestParseEntries(t *testing.T) {
+	// The malformed lines are skipped, the other ones kept.
+	data := `
+1.1.1.1:1111
+1.1.1.2
+1.1.1.3:1111 zone
+1.1.1.[4:5]:1111
+`
+	entries := parseEntries("cluster", []byte(data))
+	assert.Equal(t, len(entries), 3)
+	assert.Equal(t, entries[0].String(), "1.1.1.1:1111")
+	assert.Equal(t, entries[1].String(), "1.1.1.4:1111")
+	assert.Equal(t, entries[2].String(), "1.1.1.5:1111")
+}
+
+func TestFetchDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-discovery")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.conf"), []byte("1.1.1.1:1111\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "b.conf"), []byte("2.2.2.2:2222 zone=us-east\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "c.conf.bak"), []byte("3.3.3.3:3333\n"), 0644))
+
+	d := &FileDiscoveryService{}
+	assert.NoError(t, d.Initialize(dir, 1))
+	entries, err := d.Fetch()
+	assert.NoError(t, err)
+	assert.Equal(t, len(entries), 2)
+	assert.Equal(t, entries[0].String(), "1.1.1.1:1111")
+	assert.Equal(t, entries[1].String(), "2.2.2.2:2222")
+	assert.Equal(t, entries[1].Labels["zone"], "us-east")
+}
+
+// Wait for the next entries the watch calls back with.
+func nextEntries(t *testing.T, watched chan []*discovery.Entry) []*discovery.Entry {
+	select {
+	case entries := <-watched:
+		return entries
+	case <-time.After(5 * time.Second):
+		t.Fatal("the watch never called back")
+	}
+	return nil
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-discovery")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cluster")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("1.1.1.1:1111\n"), 0644))
+
+	d := &FileDiscoveryService{}
+	assert.NoError(t, d.Initialize(path, 60))
+	watched := make(chan []*discovery.Entry, 10)
+	go d.Watch(func(entries []*discovery.Entry) { watched <- entries })
+	time.Sleep(100 * time.Millisecond)
+
+	// Rewriting the same nodes, or other files, doesn't call back.
+	assert.NoError(t, ioutil.WriteFile(path, []byte("# same nodes\n1.1.1.1:1111\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "other"), []byte("2.2.2.2:2222\n"), 0644))
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("1.1.1.1:1111\n2.2.2.2:2222\n"), 0644))
+	entries := nextEntries(t, watched)
+	assert.Equal(t, len(entries), 2)
+	assert.Equal(t, entries[1].String(), "2.2.2.2:2222")
+
+	// Files replaced by a rename are seen too.
+	tmp := filepath.Join(dir, "cluster.tmp")
+	assert.NoError(t, ioutil.WriteFile(tmp, []byte("2.2.2.2:2222\n"), 0644))
+	assert.NoError(t, os.Rename(tmp, path))
+	entries = nextEntries(t, watched)
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].String(), "2.2.2.2:2222")
+	assert.Equal(t, len(watched), 0)
+}
+
+func TestWatchDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-discovery")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "a.conf"), []byte("1.1.1.1:1111\n"), 0644))
+
+	d := &FileDiscoveryService{}
+	assert.NoError(t, d.Initialize(dir, 60))
+	watched := make(chan []*discovery.Entry, 10)
+	go d.Watch(func(entries []*discovery.Entry) { watched <- entries })
+	time.Sleep(100 * time.Millisecond)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "b.conf"), []byte("2.2.2.2:2222\n"), 0644))
+	entries := nextEntries(t, watched)
+	assert.Equal(t, len(entries), 2)
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "a.conf")))
+	entries = nextEntries(t, watched)
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].String(), "2.2.2.2:2222")
+}
+
+func TestWatchUnchanged(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-discovery")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cluster")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("1.1.1.1:1111\n"), 0644))
+
+	// Neither the heartbeats nor rewriting the same nodes call back.
+	d := &FileDiscoveryService{}
+	assert.NoError(t, d.Initialize(path, 1))
+	watched := make(chan []*discovery.Entry, 10)
+	go d.Watch(func(entries []*discovery.Entry) { watched <- entries })
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, ioutil.WriteFile(path, []byte("1.1.1.1:1111\n"), 0644))
+
+	select {
+	case entries := <-watched:
+		t.Fatalf("called back with unchanged nodes %v", entries)
+	case <-time.After(2500 * time.Millisecond):
+	}
 }
 
 func TestRegister(t *testing.T) {
@@ -0,0 +1,10 @@
+// +build !linux
+
+package file
+
+import "errors"
+
+// Only linux can watch directories, the other platforms poll them.
+func watchDir(dir string) (<-chan string, error) {
+	return nil, errors.New("watching files is not supported on this platform")
+}
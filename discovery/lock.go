@@ -0,0 +1,82 @@
+package discovery
+
+import (
+	"sync"
+	"time"
+)
+
+// Locker is implemented by the discovery services backed by a distributed
+// store, which can hold expiring locks. It is used to elect a leader among
+// several managers.
+type Locker interface {
+	// Lock tries to take `key` and store `value` in it for `ttl`. If the lock
+	// is already held with the same value, its TTL is refreshed.
+	Lock(key, value string, ttl time.Duration) (bool, error)
+
+	// Unlock releases `key` if it is held with `value`.
+	Unlock(key, value string) error
+
+	// Holder returns the value stored by the current holder of `key`, or an
+	// empty string if nobody holds it.
+	Holder(key string) (string, error)
+}
+
+type memoryLock struct {
+	value   string
+	expires time.Time
+}
+
+// MemoryLocker is an in-memory Locker, for a single process and tests.
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]*memoryLock
+
+	// Now returns the current time, it can be overridden by tests.
+	Now func() time.Time
+}
+
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{
+		locks: make(map[string]*memoryLock),
+		Now:   time.Now,
+	}
+}
+
+// Return the lock for `key`, nil if it's free or has expired.
+func (l *MemoryLocker) get(key string) *memoryLock {
+	if lock, exists := l.locks[key]; exists && l.Now().Before(lock.expires) {
+		return lock
+	}
+	return nil
+}
+
+func (l *MemoryLocker) Lock(key, value string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lock := l.get(key); lock != nil && lock.value != value {
+		return false, nil
+	}
+	l.locks[key] = &memoryLock{value: value, expires: l.Now().Add(ttl)}
+	return true, nil
+}
+
+func (l *MemoryLocker) Unlock(key, value string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lock := l.get(key); lock != nil && lock.value == value {
+		delete(l.locks, key)
+	}
+	return nil
+}
+
+func (l *MemoryLocker) Holder(key string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lock := l.get(key); lock != nil {
+		return lock.value, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,48 @@
+package discovery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLocker(t *testing.T) {
+	now := time.Now()
+	locker := NewMemoryLocker()
+	locker.Now = func() time.Time { return now }
+
+	holder, err := locker.Holder("key")
+	assert.NoError(t, err)
+	assert.Equal(t, holder, "")
+
+	// Take the lock, then refresh it.
+	locked, err := locker.Lock("key", "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+	locked, err = locker.Lock("key", "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	// Somebody else can't take it until it expires.
+	locked, err = locker.Lock("key", "b", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, locked)
+	holder, _ = locker.Holder("key")
+	assert.Equal(t, holder, "a")
+
+	now = now.Add(2 * time.Minute)
+	holder, _ = locker.Holder("key")
+	assert.Equal(t, holder, "")
+	locked, err = locker.Lock("key", "b", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	// Only the holder can unlock.
+	assert.NoError(t, locker.Unlock("key", "a"))
+	holder, _ = locker.Holder("key")
+	assert.Equal(t, holder, "b")
+	assert.NoError(t, locker.Unlock("key", "b"))
+	holder, _ = locker.Holder("key")
+	assert.Equal(t, holder, "")
+}
@@ -0,0 +1,83 @@
+package discovery
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+// Options of a discovery service, given in the query of its URL such as
+// `consul://10.0.0.1:8500/swarm?tlscacert=ca.pem&token=secret`. `tls` talks
+// to the backend over TLS, which the other tls options imply. `tlscacert`
+// trusts only the certificates signed by this CA and `tlscert` and `tlskey`
+// authenticate with a client certificate. `username` and `password`, or the
+// consul ACL `token`, authenticate with credentials.
+type Options struct {
+	url.Values
+}
+
+// Split the query off `uri` and parse it.
+func ParseOptions(uri string) (string, Options, error) {
+	parts := strings.SplitN(uri, "?", 2)
+	if len(parts) == 1 {
+		return uri, Options{url.Values{}}, nil
+	}
+
+	values, err := url.ParseQuery(parts[1])
+	if err != nil {
+		return "", Options{}, err
+	}
+	for key := range values {
+		switch key {
+		case "tls", "tlscacert", "tlscert", "tlskey", "username", "password", "token":
+		default:
+			return "", Options{}, fmt.Errorf("unknown discovery option %q", key)
+		}
+	}
+	return parts[0], Options{values}, nil
+}
+
+// Return whether the backend must be reached over TLS.
+func (o Options) UseTLS() bool {
+	switch o.Get("tls") {
+	case "1", "true":
+		return true
+	}
+	return o.Get("tlscacert") != "" || o.Get("tlscert") != "" || o.Get("tlskey") != ""
+}
+
+// Return the TLS configuration to reach the backend with, or nil when TLS
+// isn't used.
+func (o Options) TLSConfig() (*tls.Config, error) {
+	if !o.UseTLS() {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS10}
+	if ca := o.Get("tlscacert"); ca != "" {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't read CA certificate: %s", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Couldn't parse CA certificate %s", ca)
+		}
+	}
+
+	cert, key := o.Get("tlscert"), o.Get("tlskey")
+	if (cert == "") != (key == "") {
+		return nil, fmt.Errorf("tlscert and tlskey must be given together")
+	}
+	if cert != "" {
+		certificate, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't load X509 key pair: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
@@ -0,0 +1,48 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOptions(t *testing.T) {
+	uri, options, err := ParseOptions("127.0.0.1/path")
+	assert.NoError(t, err)
+	assert.Equal(t, uri, "127.0.0.1/path")
+	assert.False(t, options.UseTLS())
+
+	uri, options, err = ParseOptions("127.0.0.1/path?token=secret&username=swarm&password=pass")
+	assert.NoError(t, err)
+	assert.Equal(t, uri, "127.0.0.1/path")
+	assert.Equal(t, options.Get("token"), "secret")
+	assert.Equal(t, options.Get("username"), "swarm")
+	assert.Equal(t, options.Get("password"), "pass")
+	assert.False(t, options.UseTLS())
+
+	_, _, err = ParseOptions("127.0.0.1/path?foo=bar")
+	assert.Error(t, err)
+}
+
+func TestTLSConfig(t *testing.T) {
+	_, options, _ := ParseOptions("127.0.0.1/path")
+	config, err := options.TLSConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+
+	_, options, _ = ParseOptions("127.0.0.1/path?tls=true")
+	assert.True(t, options.UseTLS())
+	config, err = options.TLSConfig()
+	assert.NoError(t, err)
+	assert.NotNil(t, config)
+	assert.Nil(t, config.RootCAs)
+
+	_, options, _ = ParseOptions("127.0.0.1/path?tlscacert=/does/not/exist")
+	assert.True(t, options.UseTLS())
+	_, err = options.TLSConfig()
+	assert.Error(t, err)
+
+	_, options, _ = ParseOptions("127.0.0.1/path?tlscert=cert.pem")
+	_, err = options.TLSConfig()
+	assert.Error(t, err)
+}
@@ -1,7 +1,9 @@
 package zookeeper
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net"
 	"path"
 	"strings"
 	"time"
@@ -15,6 +17,7 @@ type ZkDiscoveryService struct {
 	conn      *zk.Conn
 	path      []string
 	heartbeat int
+	acl       []zk.ACL
 }
 
 func init() {
@@ -28,7 +31,7 @@ func (s *ZkDiscoveryService) fullpath() string {
 func (s *ZkDiscoveryService) createFullpath() error {
 	for i := 1; i <= len(s.path); i++ {
 		newpath := "/" + strings.Join(s.path[:i], "/")
-		_, err := s.conn.Create(newpath, []byte{1}, 0, zk.WorldACL(zk.PermAll))
+		_, err := s.conn.Create(newpath, []byte{1}, 0, s.acl)
 		if err != nil {
 			// It's OK if key already existed. Just skip.
 			if err != zk.ErrNodeExists {
@@ -40,6 +43,11 @@ func (s *ZkDiscoveryService) createFullpath() error {
 }
 
 func (s *ZkDiscoveryService) Initialize(uris string, heartbeat int) error {
+	uris, options, err := discovery.ParseOptions(uris)
+	if err != nil {
+		return err
+	}
+
 	var (
 		// split here because uris can contain multiples ips
 		// like `zk://192.168.0.1,192.168.0.2,192.168.0.3/path`
@@ -57,11 +65,32 @@ func (s *ZkDiscoveryService) Initialize(uris string, heartbeat int) error {
 		s.path = []string{parts[1]}
 	}
 
-	conn, _, err := zk.Connect(ips, time.Second)
+	tlsConfig, err := options.TLSConfig()
+	if err != nil {
+		return err
+	}
+	dialer := net.DialTimeout
+	if tlsConfig != nil {
+		dialer = func(network, address string, timeout time.Duration) (net.Conn, error) {
+			return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, network, address, tlsConfig)
+		}
+	}
+
+	conn, _, err := zk.ConnectWithDialer(ips, time.Second, dialer)
 	if err != nil {
 		return err
 	}
 
+	// With credentials, the nodes we create can only be changed by us.
+	s.acl = zk.WorldACL(zk.PermAll)
+	if username := options.Get("username"); username != "" {
+		if err := conn.AddAuth("digest", []byte(username+":"+options.Get("password"))); err != nil {
+			conn.Close()
+			return err
+		}
+		s.acl = append(zk.DigestACL(zk.PermAll, username, options.Get("password")), zk.WorldACL(zk.PermRead)...)
+	}
+
 	s.conn = conn
 	s.heartbeat = heartbeat
 	err = s.createFullpath()
@@ -139,6 +168,62 @@ func (s *ZkDiscoveryService) Register(addr string) error {
 	}
 
 	// create the node path to store address information
-	_, err = s.conn.Create(nodePath, []byte(addr), 0, zk.WorldACL(zk.PermAll))
+	_, err = s.conn.Create(nodePath, []byte(addr), 0, s.acl)
 	return err
 }
+
+// Lock creates `key` as an ephemeral node, which lives as long as our session.
+// The TTL is ruled by the session timeout.
+func (s *ZkDiscoveryService) Lock(key, value string, _ time.Duration) (bool, error) {
+	lockPath := "/" + strings.Trim(key, "/")
+
+	// Create the parents of the lock first.
+	parts := strings.Split(strings.Trim(key, "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		parent := "/" + strings.Join(parts[:i], "/")
+		if _, err := s.conn.Create(parent, []byte{1}, 0, s.acl); err != nil && err != zk.ErrNodeExists {
+			return false, err
+		}
+	}
+
+	_, err := s.conn.Create(lockPath, []byte(value), zk.FlagEphemeral, s.acl)
+	if err == nil {
+		return true, nil
+	}
+	if err != zk.ErrNodeExists {
+		return false, err
+	}
+
+	holder, err := s.Holder(key)
+	if err != nil {
+		return false, err
+	}
+	return holder == value, nil
+}
+
+func (s *ZkDiscoveryService) Unlock(key, value string) error {
+	lockPath := "/" + strings.Trim(key, "/")
+
+	data, stat, err := s.conn.Get(lockPath)
+	if err != nil {
+		if err == zk.ErrNoNode {
+			return nil
+		}
+		return err
+	}
+	if string(data) != value {
+		return nil
+	}
+	return s.conn.Delete(lockPath, stat.Version)
+}
+
+func (s *ZkDiscoveryService) Holder(key string) (string, error) {
+	data, _, err := s.conn.Get("/" + strings.Trim(key, "/"))
+	if err != nil {
+		if err == zk.ErrNoNode {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(data), nil
+}
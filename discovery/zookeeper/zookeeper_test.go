@@ -1,8 +1,22 @@
 package zookeeper
 
 import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/samuel/go-zookeeper/zk"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,3 +34,373 @@ func TestInitialize(t *testing.T) {
 	assert.Error(t, service.Initialize("127.0.0.1,127.0.0.2,127.0.0.3/path/sub1/sub2", 0))
 	assert.Equal(t, service.fullpath(), "/path/sub1/sub2")
 }
+
+// Count the TLS handshakes completed on a listener, then hang up: a fake
+// zookeeper server that only checks how the client reaches it.
+func listenTLS(t *testing.T) (net.Listener, *tls.Config, chan bool) {
+	server := httptest.NewUnstartedServer(nil)
+	server.StartTLS()
+	config := &tls.Config{Certificates: server.TLS.Certificates}
+	server.Close()
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	assert.NoError(t, err)
+	handshakes := make(chan bool, 100)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			handshakes <- conn.(*tls.Conn).Handshake() == nil
+			conn.Close()
+		}
+	}()
+	return l, config, handshakes
+}
+
+func TestInitializeTLS(t *testing.T) {
+	l, config, handshakes := listenTLS(t)
+	defer l.Close()
+
+	file, err := ioutil.TempFile("", "zk-ca")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	assert.NoError(t, pem.Encode(file, &pem.Block{Type: "CERTIFICATE", Bytes: config.Certificates[0].Certificate[0]}))
+	file.Close()
+
+	service := &ZkDiscoveryService{}
+	assert.Error(t, service.Initialize(l.Addr().String()+"/path?tlscacert="+file.Name(), 0))
+	assert.True(t, <-handshakes)
+
+	// The server isn't trusted without its CA.
+	for len(handshakes) > 0 {
+		<-handshakes
+	}
+	assert.Error(t, service.Initialize(l.Addr().String()+"/path?tls=true", 0))
+	assert.False(t, <-handshakes)
+}
+
+// A znode of the fake zookeeper server.
+type fakeZnode struct {
+	data []byte
+	acl  []zk.ACL
+}
+
+// An in-process zookeeper server speaking just enough of the protocol for the
+// discovery, and checking the ACLs of the nodes against the digest
+// credentials of each session.
+type fakeZk struct {
+	sync.Mutex
+	nodes    map[string]*fakeZnode
+	sessions int64
+}
+
+func newFakeZk(t *testing.T) (*fakeZk, net.Listener) {
+	f := &fakeZk{nodes: map[string]*fakeZnode{"/": {acl: zk.WorldACL(zk.PermAll)}}}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, l
+}
+
+// Read the fields of a request, in the jute encoding of zookeeper.
+type zkDecoder struct {
+	buf []byte
+}
+
+func (d *zkDecoder) int32() int32 {
+	if len(d.buf) < 4 {
+		d.buf = nil
+		return 0
+	}
+	v := int32(binary.BigEndian.Uint32(d.buf))
+	d.buf = d.buf[4:]
+	return v
+}
+
+func (d *zkDecoder) int64() int64 {
+	return int64(d.int32())<<32 | int64(uint32(d.int32()))
+}
+
+func (d *zkDecoder) bool() bool {
+	if len(d.buf) < 1 {
+		return false
+	}
+	v := d.buf[0] != 0
+	d.buf = d.buf[1:]
+	return v
+}
+
+func (d *zkDecoder) bytes() []byte {
+	n := int(d.int32())
+	if n < 0 || n > len(d.buf) {
+		return nil
+	}
+	v := d.buf[:n]
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *zkDecoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *zkDecoder) acl() []zk.ACL {
+	acl := []zk.ACL{}
+	for n := d.int32(); n > 0; n-- {
+		acl = append(acl, zk.ACL{Perms: d.int32(), Scheme: d.string(), ID: d.string()})
+	}
+	return acl
+}
+
+// Write the fields of a response.
+type zkEncoder struct {
+	bytes.Buffer
+}
+
+func (e *zkEncoder) int32(v int32) { binary.Write(e, binary.BigEndian, v) }
+func (e *zkEncoder) int64(v int64) { binary.Write(e, binary.BigEndian, v) }
+
+func (e *zkEncoder) string(v string) {
+	e.int32(int32(len(v)))
+	e.WriteString(v)
+}
+
+// An empty stat: the discovery never looks at it.
+func (e *zkEncoder) stat() {
+	e.Write(make([]byte, 68))
+}
+
+func readZkPacket(r io.Reader) (*zkDecoder, error) {
+	var n int32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return &zkDecoder{buf: buf}, nil
+}
+
+func writeZkPacket(w io.Writer, e *zkEncoder) error {
+	if err := binary.Write(w, binary.BigEndian, int32(e.Len())); err != nil {
+		return err
+	}
+	_, err := w.Write(e.Bytes())
+	return err
+}
+
+func (f *fakeZk) serve(conn net.Conn) {
+	defer conn.Close()
+
+	// Connect request: protocol version, last zxid, timeout, session ID and
+	// password.
+	req, err := readZkPacket(conn)
+	if err != nil {
+		return
+	}
+	req.int32()
+	req.int64()
+	timeout := req.int32()
+
+	f.Lock()
+	f.sessions++
+	session := f.sessions
+	f.Unlock()
+
+	res := &zkEncoder{}
+	res.int32(0)
+	res.int32(timeout)
+	res.int64(session)
+	res.string(strings.Repeat("\x00", 16))
+	if writeZkPacket(conn, res) != nil {
+		return
+	}
+
+	// The digest IDs the session authenticated as.
+	auths := []string{}
+	for {
+		req, err := readZkPacket(conn)
+		if err != nil {
+			return
+		}
+		xid, opcode := req.int32(), req.int32()
+
+		body := &zkEncoder{}
+		code := f.handle(opcode, req, body, &auths)
+
+		res := &zkEncoder{}
+		res.int32(xid)
+		res.int64(0)
+		res.int32(code)
+		if code == 0 {
+			res.Write(body.Bytes())
+		}
+		if writeZkPacket(conn, res) != nil || opcode == -11 {
+			return
+		}
+	}
+}
+
+// Return whether the ACL of `node` grants `perm` to a session authenticated
+// as `auths`.
+func allowed(node *fakeZnode, perm int32, auths []string) bool {
+	for _, acl := range node.acl {
+		if acl.Perms&perm == 0 {
+			continue
+		}
+		if acl.Scheme == "world" && acl.ID == "anyone" {
+			return true
+		}
+		for _, auth := range auths {
+			if acl.Scheme == "digest" && acl.ID == auth {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Run the request `opcode`, write its response body to `res` and return the
+// error code.
+func (f *fakeZk) handle(opcode int32, req *zkDecoder, res *zkEncoder, auths *[]string) int32 {
+	const (
+		errNoNode     = -101
+		errNoAuth     = -102
+		errNodeExists = -110
+		errNotEmpty   = -111
+	)
+
+	f.Lock()
+	defer f.Unlock()
+
+	switch opcode {
+	case 1: // create
+		p, data, acl := req.string(), req.bytes(), req.acl()
+		parent, exists := f.nodes[path.Dir(p)]
+		if !exists {
+			return errNoNode
+		}
+		if _, exists := f.nodes[p]; exists {
+			return errNodeExists
+		}
+		if !allowed(parent, zk.PermCreate, *auths) {
+			return errNoAuth
+		}
+		f.nodes[p] = &fakeZnode{data: data, acl: acl}
+		res.string(p)
+	case 2: // delete
+		p := req.string()
+		if _, exists := f.nodes[p]; !exists {
+			return errNoNode
+		}
+		if !allowed(f.nodes[path.Dir(p)], zk.PermDelete, *auths) {
+			return errNoAuth
+		}
+		if len(f.children(p)) > 0 {
+			return errNotEmpty
+		}
+		delete(f.nodes, p)
+	case 3: // exists
+		if _, exists := f.nodes[req.string()]; !exists {
+			return errNoNode
+		}
+		res.stat()
+	case 4: // getData
+		node, exists := f.nodes[req.string()]
+		if !exists {
+			return errNoNode
+		}
+		if !allowed(node, zk.PermRead, *auths) {
+			return errNoAuth
+		}
+		res.int32(int32(len(node.data)))
+		res.Write(node.data)
+		res.stat()
+	case 12: // getChildren2
+		p := req.string()
+		node, exists := f.nodes[p]
+		if !exists {
+			return errNoNode
+		}
+		if !allowed(node, zk.PermRead, *auths) {
+			return errNoAuth
+		}
+		children := f.children(p)
+		res.int32(int32(len(children)))
+		for _, child := range children {
+			res.string(child)
+		}
+		res.stat()
+	case 100: // setAuth
+		req.int32()
+		scheme, auth := req.string(), req.string()
+		if parts := strings.SplitN(auth, ":", 2); scheme == "digest" && len(parts) == 2 {
+			*auths = append(*auths, zk.DigestACL(zk.PermAll, parts[0], parts[1])[0].ID)
+		}
+	}
+	// Anything else, such as ping and close, just succeeds.
+	return 0
+}
+
+// Return the sorted names of the children of `p`.
+func (f *fakeZk) children(p string) []string {
+	children := []string{}
+	for child := range f.nodes {
+		if child != "/" && path.Dir(child) == p {
+			children = append(children, path.Base(child))
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+func TestFakeZookeeper(t *testing.T) {
+	f, l := newFakeZk(t)
+	defer l.Close()
+	addr := l.Addr().String()
+
+	// With credentials, the path is created for the manager's digest only,
+	// and the others can read it.
+	owner := &ZkDiscoveryService{}
+	assert.NoError(t, owner.Initialize(addr+"/swarm?username=swarm&password=secret", 1))
+	defer owner.conn.Close()
+	f.Lock()
+	acl := f.nodes["/swarm"].acl
+	f.Unlock()
+	assert.Equal(t, acl, append(zk.DigestACL(zk.PermAll, "swarm", "secret"), zk.WorldACL(zk.PermRead)...))
+
+	assert.NoError(t, owner.Register("1.1.1.1:1111"))
+	// Registering again replaces the node.
+	assert.NoError(t, owner.Register("1.1.1.1:1111"))
+
+	// Without the credentials, or with wrong ones, the nodes can be read but
+	// not registered.
+	for _, query := range []string{"", "?username=swarm&password=wrong"} {
+		other := &ZkDiscoveryService{}
+		assert.NoError(t, other.Initialize(addr+"/swarm"+query, 1))
+		entries, err := other.Fetch()
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, entries[0].String(), "1.1.1.1:1111")
+		assert.Equal(t, other.Register("2.2.2.2:2222"), zk.ErrNoAuth)
+		other.conn.Close()
+	}
+
+	assert.NoError(t, owner.Register("2.2.2.2:2222"))
+	entries, err := owner.Fetch()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, entries[0].String(), "1.1.1.1:1111")
+	assert.Equal(t, entries[1].String(), "2.2.2.2:2222")
+}
@@ -33,6 +33,12 @@ var (
 		Value: homepath(".swarm"),
 		Usage: "",
 	}
+	flStateStore = cli.StringFlag{
+		Name:   "store",
+		Value:  "",
+		Usage:  "store of the requested state: <path>, consul://<ip>/<path>, etcd://<ip1>,<ip2>/<path> or zk://<ip1>,<ip2>/<path>; defaults to <rootdir>/state",
+		EnvVar: "SWARM_STORE",
+	}
 	flAddr = cli.StringFlag{
 		Name:   "addr",
 		Value:  "127.0.0.1:2375",
@@ -49,11 +55,69 @@ var (
 		Usage:  "ip/socket to listen on",
 		EnvVar: "SWARM_HOST",
 	}
+	flManager = cli.StringFlag{
+		Name:   "host, H",
+		Value:  "tcp://127.0.0.1:2375",
+		Usage:  "ip/socket of the manager",
+		EnvVar: "SWARM_HOST",
+	}
+	flRebalanceRatio = cli.Float64Flag{
+		Name:  "ratio",
+		Value: 0.8,
+		Usage: "highest share of their CPUs or memory the nodes should have reserved",
+	}
+	flDryRun = cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "print the plan without moving any container",
+	}
+	flOptIn = cli.BoolFlag{
+		Name:  "opt-in",
+		Usage: "only move the containers created with the rebalance:true hint",
+	}
 	flHeartBeat = cli.IntFlag{
 		Name:  "heartbeat, hb",
 		Value: 25,
 		Usage: "time in second between each heartbeat",
 	}
+	flRescheduleGrace = cli.IntFlag{
+		Name:  "reschedule-grace",
+		Value: 0,
+		Usage: "time in second before re-creating the containers of a dead node elsewhere, 0 to disable",
+	}
+	flRemoveGrace = cli.IntFlag{
+		Name:  "remove-grace",
+		Value: 60,
+		Usage: "time in second before removing a node which left the discovery service, 0 to remove it right away",
+	}
+	flWarmup = cli.StringSliceFlag{
+		Name:  "warmup",
+		Value: &cli.StringSlice{},
+		Usage: "image to pull on the new nodes before scheduling on them, \"state\" for every image of the requested state",
+	}
+	flPullParallelism = cli.IntFlag{
+		Name:  "pull-parallelism",
+		Value: 0,
+		Usage: "number of nodes pulling an image at the same time, 0 for no limit",
+	}
+	flReplication = cli.BoolFlag{
+		Name:  "replication",
+		Usage: "run several managers, electing a leader through the discovery service [consul, etcd, zk]",
+	}
+	flAdvertise = cli.StringFlag{
+		Name:   "advertise",
+		Usage:  "address of this manager advertised to the other replicas, ip:port",
+		EnvVar: "SWARM_ADVERTISE",
+	}
+	flReplicationKey = cli.StringFlag{
+		Name:  "replication-key",
+		Value: "docker/swarm/leader",
+		Usage: "key of the leader lock in the discovery service",
+	}
+	flReplicationTTL = cli.IntFlag{
+		Name:  "replication-ttl",
+		Value: 15,
+		Usage: "time in second before a dead leader is replaced",
+	}
 	flEnableCors = cli.BoolFlag{
 		Name:  "api-enable-cors, cors",
 		Usage: "enable CORS headers in the remote API",
@@ -85,17 +149,17 @@ var (
 	}
 	flStrategy = cli.StringFlag{
 		Name:  "strategy",
-		Usage: "placement strategy to use [binpacking, random]",
+		Usage: "placement strategy to use [binpacking, spread, random, external://<host>:<port>/<path>]",
 		Value: "binpacking",
 	}
 
 	// hack for go vet
-	flFilterValue         = cli.StringSlice([]string{"constraint", "affinity", "health", "port", "dependency"})
+	flFilterValue         = cli.StringSlice([]string{"constraint", "affinity", "health", "cordon", "port", "dependency", "topology"})
 	DEFAULT_FILTER_NUMBER = len(flFilterValue)
 
 	flFilter = cli.StringSliceFlag{
 		Name:  "filter, f",
-		Usage: "filter to use [constraint, affinity, health, port, dependency]",
+		Usage: "filter to use [constraint, affinity, health, cordon, port, dependency, topology]",
 		Value: &flFilterValue,
 	}
 	flCluster = cli.StringFlag{
@@ -0,0 +1,99 @@
+package leadership
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/discovery"
+)
+
+// Candidate runs for the leadership of the managers sharing the same lock.
+// Every manager advertises its address, the one holding the lock leads and the
+// others follow it.
+type Candidate struct {
+	sync.RWMutex
+
+	locker discovery.Locker
+	key    string
+	addr   string
+	ttl    time.Duration
+
+	leader   string
+	isLeader bool
+
+	// OnChange, if set, is called every time a new leader is elected.
+	OnChange func(leader string)
+}
+
+func NewCandidate(locker discovery.Locker, key, addr string, ttl time.Duration) *Candidate {
+	return &Candidate{
+		locker: locker,
+		key:    key,
+		addr:   addr,
+		ttl:    ttl,
+	}
+}
+
+// IsLeader returns true if this manager currently leads the cluster.
+func (c *Candidate) IsLeader() bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.isLeader
+}
+
+// Leader returns the address of the current leader, empty if unknown.
+func (c *Candidate) Leader() string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.leader
+}
+
+// Run campaigns for the leadership forever, refreshing the lock well before
+// it expires.
+func (c *Candidate) Run() {
+	for {
+		c.campaign()
+		time.Sleep(c.ttl / 3)
+	}
+}
+
+// Resign releases the leadership, if we hold it.
+func (c *Candidate) Resign() error {
+	c.Lock()
+	c.isLeader = false
+	c.Unlock()
+	return c.locker.Unlock(c.key, c.addr)
+}
+
+// campaign tries to take (or keep) the lock once, and updates the leader.
+func (c *Candidate) campaign() {
+	acquired, err := c.locker.Lock(c.key, c.addr, c.ttl)
+	if err != nil {
+		log.WithField("key", c.key).Errorf("Leader election failed: %v", err)
+	}
+
+	leader := c.addr
+	if !acquired {
+		if leader, err = c.locker.Holder(c.key); err != nil {
+			log.WithField("key", c.key).Errorf("Unable to find the leader: %v", err)
+		}
+	}
+
+	c.Lock()
+	changed := leader != c.leader
+	c.leader = leader
+	c.isLeader = acquired
+	c.Unlock()
+
+	if changed {
+		if acquired {
+			log.WithField("addr", c.addr).Info("Elected as the leader")
+		} else {
+			log.WithField("leader", leader).Info("Following the leader")
+		}
+		if c.OnChange != nil {
+			c.OnChange(leader)
+		}
+	}
+}
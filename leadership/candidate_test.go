@@ -0,0 +1,55 @@
+package leadership
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/swarm/discovery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCandidate(t *testing.T) {
+	var (
+		now     = time.Now()
+		locker  = discovery.NewMemoryLocker()
+		changes = []string{}
+		onc     = func(leader string) { changes = append(changes, leader) }
+	)
+	locker.Now = func() time.Time { return now }
+
+	c1 := NewCandidate(locker, "swarm/leader", "manager-1:2375", 30*time.Second)
+	c1.OnChange = onc
+	c2 := NewCandidate(locker, "swarm/leader", "manager-2:2375", 30*time.Second)
+
+	// The first candidate takes the lead.
+	c1.campaign()
+	c2.campaign()
+	assert.True(t, c1.IsLeader())
+	assert.False(t, c2.IsLeader())
+	assert.Equal(t, c1.Leader(), "manager-1:2375")
+	assert.Equal(t, c2.Leader(), "manager-1:2375")
+
+	// Refreshing the lock keeps the leader.
+	now = now.Add(20 * time.Second)
+	c1.campaign()
+	now = now.Add(20 * time.Second)
+	c2.campaign()
+	assert.True(t, c1.IsLeader())
+	assert.False(t, c2.IsLeader())
+
+	// The leader dies and its lock expires: the second candidate takes over.
+	now = now.Add(time.Minute)
+	c2.campaign()
+	c1.campaign()
+	assert.True(t, c2.IsLeader())
+	assert.False(t, c1.IsLeader())
+	assert.Equal(t, c1.Leader(), "manager-2:2375")
+
+	// Resigning frees the lock right away.
+	assert.NoError(t, c2.Resign())
+	assert.False(t, c2.IsLeader())
+	c1.campaign()
+	assert.True(t, c1.IsLeader())
+
+	assert.Equal(t, changes, []string{"manager-1:2375", "manager-2:2375", "manager-1:2375"})
+}
@@ -8,9 +8,13 @@ import (
 	"github.com/codegangsta/cli"
 	"github.com/docker/swarm/discovery"
 	_ "github.com/docker/swarm/discovery/ansible"
+	_ "github.com/docker/swarm/discovery/consul"
+	_ "github.com/docker/swarm/discovery/dns"
+	_ "github.com/docker/swarm/discovery/etcd"
 	_ "github.com/docker/swarm/discovery/file"
 	_ "github.com/docker/swarm/discovery/nodes"
 	"github.com/docker/swarm/discovery/token"
+	_ "github.com/docker/swarm/discovery/zookeeper"
 	"github.com/docker/swarm/version"
 )
 
@@ -99,13 +103,44 @@ func main() {
 			ShortName: "m",
 			Usage:     "manage a docker cluster",
 			Flags: []cli.Flag{
-				flStore, flCluster,
+				flStore, flStateStore, flCluster,
 				flStrategy, flFilter,
-				flHosts, flHeartBeat, flOverCommit,
+				flHosts, flHeartBeat, flOverCommit, flRescheduleGrace, flRemoveGrace, flPullParallelism, flWarmup,
+				flReplication, flAdvertise, flReplicationKey, flReplicationTTL,
 				flTls, flTlsCaCert, flTlsCert, flTlsKey, flTlsVerify,
 				flEnableCors},
 			Action: manage,
 		},
+		{
+			Name:   "rebalance",
+			Usage:  "move containers off the overloaded nodes",
+			Flags:  []cli.Flag{flManager, flRebalanceRatio, flDryRun, flOptIn, flTls, flTlsCaCert, flTlsCert, flTlsKey, flTlsVerify},
+			Action: rebalance,
+		},
+		{
+			Name:  "node",
+			Usage: "manage the nodes of a cluster",
+			Subcommands: []cli.Command{
+				{
+					Name:   "cordon",
+					Usage:  "keep a node out of scheduling",
+					Flags:  []cli.Flag{flManager, flTls, flTlsCaCert, flTlsCert, flTlsKey, flTlsVerify},
+					Action: cordon,
+				},
+				{
+					Name:   "uncordon",
+					Usage:  "bring a cordoned node back into scheduling",
+					Flags:  []cli.Flag{flManager, flTls, flTlsCaCert, flTlsCert, flTlsKey, flTlsVerify},
+					Action: cordon,
+				},
+				{
+					Name:   "drain",
+					Usage:  "cordon a node and move its containers elsewhere",
+					Flags:  []cli.Flag{flManager, flTls, flTlsCaCert, flTlsCert, flTlsKey, flTlsVerify},
+					Action: drain,
+				},
+			},
+		},
 		{
 			Name:      "join",
 			ShortName: "j",
@@ -6,16 +6,20 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/cli"
 	"github.com/docker/swarm/api"
 	"github.com/docker/swarm/cluster"
 	"github.com/docker/swarm/cluster/swarm"
+	"github.com/docker/swarm/discovery"
+	"github.com/docker/swarm/leadership"
 	"github.com/docker/swarm/scheduler"
 	"github.com/docker/swarm/scheduler/filter"
 	"github.com/docker/swarm/scheduler/strategy"
 	"github.com/docker/swarm/state"
+	"github.com/samalba/dockerclient"
 )
 
 type logHandler struct {
@@ -62,12 +66,41 @@ func loadTlsConfig(ca, cert, key string, verify bool) (*tls.Config, error) {
 	return config, nil
 }
 
-func manage(c *cli.Context) {
-	var (
-		tlsConfig *tls.Config = nil
-		err       error
-	)
+// Run for the leadership of the replicated managers.
+func replicate(c *cli.Context, dflag string, eventsHandler cluster.EventHandler) *leadership.Candidate {
+	addr := c.String("advertise")
+	if !checkAddrFormat(addr) {
+		log.Fatal("--advertise should be of the form ip:port or hostname:port when using --replication")
+	}
+
+	d, err := discovery.New(dflag, c.Int("heartbeat"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	locker, ok := d.(discovery.Locker)
+	if !ok {
+		log.Fatalf("--replication is not supported by the discovery service %s", dflag)
+	}
+
+	candidate := leadership.NewCandidate(locker, c.String("replication-key"), addr, time.Duration(c.Int("replication-ttl"))*time.Second)
+	candidate.OnChange = func(leader string) {
+		eventsHandler.Handle(&cluster.Event{
+			Event: dockerclient.Event{
+				Status: "leader_change",
+				Id:     leader,
+				From:   "swarm",
+				Time:   time.Now().Unix(),
+			},
+		})
+	}
+	go candidate.Run()
+
+	return candidate
+}
 
+// Return the TLS configuration requested through the --tls* flags, nil if TLS
+// is not enabled.
+func getTlsConfig(c *cli.Context) *tls.Config {
 	// If either --tls or --tlsverify are specified, load the certificates.
 	if c.Bool("tls") || c.Bool("tlsverify") {
 		if !c.IsSet("tlscert") || !c.IsSet("tlskey") {
@@ -76,7 +109,7 @@ func manage(c *cli.Context) {
 		if c.Bool("tlsverify") && !c.IsSet("tlscacert") {
 			log.Fatal("--tlscacert must be provided when using --tlsverify")
 		}
-		tlsConfig, err = loadTlsConfig(
+		tlsConfig, err := loadTlsConfig(
 			c.String("tlscacert"),
 			c.String("tlscert"),
 			c.String("tlskey"),
@@ -84,24 +117,40 @@ func manage(c *cli.Context) {
 		if err != nil {
 			log.Fatal(err)
 		}
-	} else {
-		// Otherwise, if neither --tls nor --tlsverify are specified, abort if
-		// the other flags are passed as they will be ignored.
-		if c.IsSet("tlscert") || c.IsSet("tlskey") || c.IsSet("tlscacert") {
-			log.Fatal("--tlscert, --tlskey and --tlscacert require the use of either --tls or --tlsverify")
-		}
+		return tlsConfig
 	}
 
-	store := state.NewStore(path.Join(c.String("rootdir"), "state"))
-	if err := store.Initialize(); err != nil {
-		log.Fatal(err)
+	// Otherwise, if neither --tls nor --tlsverify are specified, abort if
+	// the other flags are passed as they will be ignored.
+	if c.IsSet("tlscert") || c.IsSet("tlskey") || c.IsSet("tlscacert") {
+		log.Fatal("--tlscert, --tlskey and --tlscacert require the use of either --tls or --tlsverify")
 	}
+	return nil
+}
+
+func manage(c *cli.Context) {
+	tlsConfig := getTlsConfig(c)
 
 	dflag := getDiscovery(c)
 	if dflag == "" {
 		log.Fatalf("discovery required to manage a cluster. See '%s manage --help'.", c.App.Name)
 	}
 
+	uri := c.String("store")
+	if uri == "" {
+		uri = path.Join(c.String("rootdir"), "state")
+	}
+	if state.Overlaps(uri, dflag) {
+		log.Fatalf("--store %s overlaps with the discovery %s, use separate paths", uri, dflag)
+	}
+	store, err := state.New(uri)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := store.Initialize(); err != nil {
+		log.Fatal(err)
+	}
+
 	s, err := strategy.New(c.String("strategy"))
 	if err != nil {
 		log.Fatal(err)
@@ -120,11 +169,24 @@ func manage(c *cli.Context) {
 	sched := scheduler.New(s, fs)
 
 	eventsHandler := api.NewEventsHandler()
+
+	var candidate *leadership.Candidate
+	if c.Bool("replication") {
+		candidate = replicate(c, dflag, eventsHandler)
+	}
+
 	options := &cluster.Options{
 		TLSConfig:       tlsConfig,
 		OvercommitRatio: c.Float64("overcommit"),
 		Discovery:       dflag,
 		Heartbeat:       c.Int("heartbeat"),
+		RescheduleGrace: c.Int("reschedule-grace"),
+		RemoveGrace:     c.Int("remove-grace"),
+		PullParallelism: c.Int("pull-parallelism"),
+		Warmup:          c.StringSlice("warmup"),
+	}
+	if candidate != nil {
+		options.IsLeader = candidate.IsLeader
 	}
 
 	cluster := swarm.NewCluster(sched, store, eventsHandler, options)
@@ -134,5 +196,5 @@ func manage(c *cli.Context) {
 	if c.IsSet("host") || c.IsSet("H") {
 		hosts = hosts[1:]
 	}
-	log.Fatal(api.ListenAndServe(cluster, hosts, c.Bool("cors"), tlsConfig, eventsHandler))
+	log.Fatal(api.ListenAndServe(cluster, hosts, c.Bool("cors"), tlsConfig, eventsHandler, candidate))
 }
@@ -0,0 +1,292 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default buckets of the latency histograms, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// A metric that can be exposed in the Prometheus text format.
+type Metric interface {
+	Write(w io.Writer) error
+}
+
+// A set of metrics exposed together.
+type Registry struct {
+	sync.RWMutex
+	metrics []Metric
+}
+
+// Every metric created by NewCounter, NewGauge and NewHistogram is registered
+// here.
+var DefaultRegistry = &Registry{}
+
+func (r *Registry) Register(m Metric) {
+	r.Lock()
+	r.metrics = append(r.metrics, m)
+	r.Unlock()
+}
+
+// Write every metric of the registry, in registration order.
+func (r *Registry) Write(w io.Writer) error {
+	r.RLock()
+	defer r.RUnlock()
+
+	for _, m := range r.metrics {
+		if err := m.Write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write every metric of the default registry.
+func Write(w io.Writer) error {
+	return DefaultRegistry.Write(w)
+}
+
+// The samples of a metric, one per set of label values.
+type family struct {
+	sync.Mutex
+	name   string
+	help   string
+	kind   string
+	labels []string
+	keys   []string
+}
+
+func newFamily(name, help, kind string, labels []string) family {
+	return family{name: name, help: help, kind: kind, labels: labels}
+}
+
+// Return the key of `values` and whether it was seen for the first time.
+// Must be called with the lock held.
+func (f *family) key(values []string) (string, bool) {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s: expected %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	for _, k := range f.keys {
+		if k == key {
+			return key, false
+		}
+	}
+	f.keys = append(f.keys, key)
+	return key, true
+}
+
+func (f *family) header(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+	return err
+}
+
+// Return the sorted keys. Must be called with the lock held.
+func (f *family) sortedKeys() []string {
+	keys := make([]string, len(f.keys))
+	copy(keys, f.keys)
+	sort.Strings(keys)
+	return keys
+}
+
+// Format the labels of `key`, followed by `extra` ones.
+func (f *family) labelString(key string, extra ...string) string {
+	pairs := []string{}
+	if len(f.labels) > 0 {
+		for i, value := range strings.Split(key, "\xff") {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", f.labels[i], escape(value)))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", extra[i], extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// %q escapes backslashes, quotes and newlines the way Prometheus expects, but
+// other control characters in a way it doesn't understand: drop them.
+func escape(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' && r != '\n' {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// A value that only goes up.
+type Counter struct {
+	family
+	values map[string]float64
+}
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{family: newFamily(name, help, "counter", labels), values: make(map[string]float64)}
+	DefaultRegistry.Register(c)
+	return c
+}
+
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *Counter) Add(v float64, values ...string) {
+	c.Lock()
+	defer c.Unlock()
+
+	key, _ := c.key(values)
+	c.values[key] += v
+}
+
+func (c *Counter) Write(w io.Writer) error {
+	c.Lock()
+	defer c.Unlock()
+
+	return writeValues(w, &c.family, c.values)
+}
+
+// A value that goes up and down.
+type Gauge struct {
+	family
+	values map[string]float64
+}
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return DefaultRegistry.NewGauge(name, help, labels...)
+}
+
+// Create a gauge registered in `r`, such as a registry built for a single
+// scrape out of the current state.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{family: newFamily(name, help, "gauge", labels), values: make(map[string]float64)}
+	r.Register(g)
+	return g
+}
+
+func (g *Gauge) Set(v float64, values ...string) {
+	g.Lock()
+	defer g.Unlock()
+
+	key, _ := g.key(values)
+	g.values[key] = v
+}
+
+// Forget every value, for gauges whose label values come and go.
+func (g *Gauge) Reset() {
+	g.Lock()
+	defer g.Unlock()
+
+	g.keys = nil
+	g.values = make(map[string]float64)
+}
+
+func (g *Gauge) Write(w io.Writer) error {
+	g.Lock()
+	defer g.Unlock()
+
+	return writeValues(w, &g.family, g.values)
+}
+
+func writeValues(w io.Writer, f *family, values map[string]float64) error {
+	if err := f.header(w); err != nil {
+		return err
+	}
+	for _, key := range f.sortedKeys() {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", f.name, f.labelString(key), formatFloat(values[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A distribution of observations, counted in buckets.
+type Histogram struct {
+	family
+	buckets []float64
+	samples map[string]*histogramSample
+}
+
+type histogramSample struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		family:  newFamily(name, help, "histogram", labels),
+		buckets: buckets,
+		samples: make(map[string]*histogramSample),
+	}
+	DefaultRegistry.Register(h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.Lock()
+	defer h.Unlock()
+
+	key, created := h.key(values)
+	if created {
+		h.samples[key] = &histogramSample{counts: make([]uint64, len(h.buckets))}
+	}
+	sample := h.samples[key]
+	for i, bound := range h.buckets {
+		if v <= bound {
+			sample.counts[i]++
+		}
+	}
+	sample.count++
+	sample.sum += v
+}
+
+// Observe the seconds elapsed since `start`.
+func (h *Histogram) Since(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+func (h *Histogram) Write(w io.Writer) error {
+	h.Lock()
+	defer h.Unlock()
+
+	if err := h.header(w); err != nil {
+		return err
+	}
+	for _, key := range h.sortedKeys() {
+		sample := h.samples[key]
+		for i, bound := range h.buckets {
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelString(key, "le", formatFloat(bound)), sample.counts[i]); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.name, h.labelString(key, "le", "+Inf"), sample.count,
+			h.name, h.labelString(key), formatFloat(sample.sum),
+			h.name, h.labelString(key), sample.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
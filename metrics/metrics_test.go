@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounter(t *testing.T) {
+	c := NewCounter("test_counter_total", "A test counter.", "route")
+	c.Inc("/b")
+	c.Inc("/a")
+	c.Add(2, "/a")
+
+	var buf bytes.Buffer
+	assert.NoError(t, c.Write(&buf))
+	assert.Equal(t, buf.String(), `# HELP test_counter_total A test counter.
+# TYPE test_counter_total counter
+test_counter_total{route="/a"} 3
+test_counter_total{route="/b"} 1
+`)
+
+	assert.Panics(t, func() { c.Inc() })
+}
+
+func TestGauge(t *testing.T) {
+	g := NewGauge("test_gauge", "A test gauge.")
+	g.Set(1.5)
+
+	var buf bytes.Buffer
+	assert.NoError(t, g.Write(&buf))
+	assert.Equal(t, buf.String(), "# HELP test_gauge A test gauge.\n# TYPE test_gauge gauge\ntest_gauge 1.5\n")
+
+	g.Reset()
+	buf.Reset()
+	assert.NoError(t, g.Write(&buf))
+	assert.Equal(t, buf.String(), "# HELP test_gauge A test gauge.\n# TYPE test_gauge gauge\n")
+
+	g = NewGauge("test_gauge_escaped", "A test gauge.", "name")
+	g.Set(1, "a\"b\\c\nd\te")
+	buf.Reset()
+	assert.NoError(t, g.Write(&buf))
+	assert.Contains(t, buf.String(), `test_gauge_escaped{name="a\"b\\c\nde"} 1`)
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram("test_seconds", "A test histogram.", []float64{0.1, 1}, "stage")
+	h.Observe(0.05, "filter")
+	h.Observe(0.5, "filter")
+	h.Observe(2, "filter")
+
+	var buf bytes.Buffer
+	assert.NoError(t, h.Write(&buf))
+	assert.Equal(t, buf.String(), `# HELP test_seconds A test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{stage="filter",le="0.1"} 1
+test_seconds_bucket{stage="filter",le="1"} 2
+test_seconds_bucket{stage="filter",le="+Inf"} 3
+test_seconds_sum{stage="filter"} 2.55
+test_seconds_count{stage="filter"} 3
+`)
+}
+
+func TestRegistry(t *testing.T) {
+	NewCounter("test_registered_total", "A registered counter.").Inc()
+
+	var buf bytes.Buffer
+	assert.NoError(t, Write(&buf))
+	assert.Contains(t, buf.String(), "test_registered_total 1\n")
+
+	// The gauges of another registry stay out of the default one.
+	r := &Registry{}
+	r.NewGauge("test_scraped", "A gauge of a single scrape.").Set(2)
+	buf.Reset()
+	assert.NoError(t, r.Write(&buf))
+	assert.Equal(t, buf.String(), "# HELP test_scraped A gauge of a single scrape.\n# TYPE test_scraped gauge\ntest_scraped 2\n")
+	buf.Reset()
+	assert.NoError(t, Write(&buf))
+	assert.NotContains(t, buf.String(), "test_scraped")
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/docker/swarm/cluster"
+)
+
+// Return the node given as argument of a `swarm node` command.
+func nodeArg(c *cli.Context) string {
+	if len(c.Args()) != 1 {
+		log.Fatalf("a node is required. See '%s %s --help'.", c.App.Name, c.Command.Name)
+	}
+	return c.Args()[0]
+}
+
+func cordon(c *cli.Context) {
+	name := nodeArg(c)
+	resp, err := callManager(c, "POST", "/swarm/nodes/"+name+"/"+c.Command.Name, url.Values{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp.Body.Close()
+	fmt.Println(name)
+}
+
+func drain(c *cli.Context) {
+	resp, err := callManager(c, "POST", "/swarm/nodes/"+nodeArg(c)+"/drain", url.Values{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	moves := []*cluster.Move{}
+	if err := json.NewDecoder(resp.Body).Decode(&moves); err != nil {
+		log.Fatal(err)
+	}
+	printMoves(moves)
+}
+
+// Print the containers moved, or planned to, by a rebalancing or a drain.
+func printMoves(moves []*cluster.Move) {
+	if len(moves) == 0 {
+		fmt.Println("Nothing to move")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 20, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tFROM\tTO\tERROR")
+	for _, move := range moves {
+		id := move.ID
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", id, move.Name, move.From, move.To, move.Error)
+	}
+	w.Flush()
+}
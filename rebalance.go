@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/codegangsta/cli"
+	"github.com/docker/swarm/cluster"
+)
+
+func rebalance(c *cli.Context) {
+	query := url.Values{}
+	query.Set("ratio", strconv.FormatFloat(c.Float64("ratio"), 'f', -1, 64))
+	if c.Bool("dry-run") {
+		query.Set("dry-run", "1")
+	}
+	if c.Bool("opt-in") {
+		query.Set("opt-in", "1")
+	}
+
+	resp, err := callManager(c, "POST", "/swarm/rebalance", query)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	moves := []*cluster.Move{}
+	if err := json.NewDecoder(resp.Body).Decode(&moves); err != nil {
+		log.Fatal(err)
+	}
+
+	printMoves(moves)
+}
@@ -13,6 +13,10 @@ import (
 type AffinityFilter struct {
 }
 
+func (f *AffinityFilter) Name() string {
+	return "affinity"
+}
+
 func (f *AffinityFilter) Filter(config *dockerclient.ContainerConfig, nodes []cluster.Node) ([]cluster.Node, error) {
 	affinities, err := parseExprs("affinity", config.Env)
 	if err != nil {
@@ -48,6 +52,10 @@ func (f *AffinityFilter) Filter(config *dockerclient.ContainerConfig, nodes []cl
 			}
 		}
 		if len(candidates) == 0 {
+			if affinity.isSoft {
+				log.Debugf("no node satisfies the soft affinity %s, ignoring it", affinity.String())
+				continue
+			}
 			return nil, fmt.Errorf("unable to find a node that satisfies %s%s%s", affinity.key, OPERATORS[affinity.operator], affinity.value)
 		}
 		nodes = candidates
@@ -193,6 +193,21 @@ func TestAffinityFilter(t *testing.T) {
 	}, nodes)
 	assert.Error(t, err)
 
+	// Soft affinities narrow the candidates when they can.
+	result, err = f.Filter(&dockerclient.ContainerConfig{
+		Env: []string{"affinity:image==~image-1"},
+	}, nodes)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, result[0], nodes[1])
+
+	// And are ignored when no node satisfies them.
+	result, err = f.Filter(&dockerclient.ContainerConfig{
+		Env: []string{"affinity:container==~does_not_exsits"},
+	}, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes)
+
 	// Not support = any more
 	result, err = f.Filter(&dockerclient.ContainerConfig{
 		Env: []string{"affinity:image=image-0:tag3"},
@@ -12,6 +12,10 @@ import (
 type ConstraintFilter struct {
 }
 
+func (f *ConstraintFilter) Name() string {
+	return "constraint"
+}
+
 func (f *ConstraintFilter) Filter(config *dockerclient.ContainerConfig, nodes []cluster.Node) ([]cluster.Node, error) {
 	constraints, err := parseExprs("constraint", config.Env)
 	if err != nil {
@@ -36,6 +40,10 @@ func (f *ConstraintFilter) Filter(config *dockerclient.ContainerConfig, nodes []
 			}
 		}
 		if len(candidates) == 0 {
+			if constraint.isSoft {
+				log.Debugf("no node satisfies the soft constraint %s, ignoring it", constraint.String())
+				continue
+			}
 			return nil, fmt.Errorf("unable to find a node that satisfies %s%s%s", constraint.key, OPERATORS[constraint.operator], constraint.value)
 		}
 		nodes = candidates
@@ -246,3 +246,62 @@ func TestUnsupportedOperators(t *testing.T) {
 	assert.Error(t, err)
 	assert.Len(t, result, 0)
 }
+
+func TestSoftConstraint(t *testing.T) {
+	var (
+		f      = ConstraintFilter{}
+		nodes  = testFixtures()
+		result []cluster.Node
+		err    error
+	)
+
+	// A soft constraint narrows the candidates when it can.
+	result, err = f.Filter(&dockerclient.ContainerConfig{Env: []string{"constraint:region==~us-east"}}, nodes)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, result[0], nodes[1])
+
+	// And is ignored when no node satisfies it.
+	result, err = f.Filter(&dockerclient.ContainerConfig{Env: []string{"constraint:region==~ap-south"}}, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes)
+
+	// Soft constraints also apply to !=.
+	result, err = f.Filter(&dockerclient.ContainerConfig{Env: []string{"constraint:group!=~1"}}, nodes)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.NotContains(t, result, nodes[0])
+	assert.NotContains(t, result, nodes[1])
+
+	// Hard constraints still apply along with the ignored soft ones.
+	result, err = f.Filter(&dockerclient.ContainerConfig{Env: []string{"constraint:group==1", "constraint:region==~eu"}}, nodes)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.NotContains(t, result, nodes[2])
+
+	result, err = f.Filter(&dockerclient.ContainerConfig{Env: []string{"constraint:region==~eu", "constraint:group==1"}}, nodes)
+	assert.Error(t, err)
+}
+
+func TestConstraintComparison(t *testing.T) {
+	var (
+		f      = ConstraintFilter{}
+		nodes  = testFixtures()
+		result []cluster.Node
+		err    error
+	)
+
+	result, err = f.Filter(&dockerclient.ContainerConfig{Env: []string{"constraint:group>=2"}}, nodes)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, result[0], nodes[2])
+
+	// Nodes without the label never match.
+	result, err = f.Filter(&dockerclient.ContainerConfig{Env: []string{"constraint:group<2"}}, nodes)
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.NotContains(t, result, nodes[3])
+
+	result, err = f.Filter(&dockerclient.ContainerConfig{Env: []string{"constraint:group>2"}}, nodes)
+	assert.Error(t, err)
+}
@@ -0,0 +1,35 @@
+package filter
+
+import (
+	"errors"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+)
+
+var (
+	ErrAllNodesCordoned = errors.New("All the nodes of the cluster are cordoned")
+)
+
+// CordonFilter doesn't schedule containers on cordoned nodes.
+type CordonFilter struct {
+}
+
+func (f *CordonFilter) Name() string {
+	return "cordon"
+}
+
+func (f *CordonFilter) Filter(_ *dockerclient.ContainerConfig, nodes []cluster.Node) ([]cluster.Node, error) {
+	result := []cluster.Node{}
+	for _, node := range nodes {
+		if !node.IsCordoned() {
+			result = append(result, node)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, ErrAllNodesCordoned
+	}
+
+	return result, nil
+}
@@ -0,0 +1,31 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCordonFilter(t *testing.T) {
+	var (
+		f     = CordonFilter{}
+		nodes = []cluster.Node{
+			&FakeNode{id: "node-0-id", name: "node-0-name"},
+			&FakeNode{id: "node-1-id", name: "node-1-name", cordoned: true},
+		}
+		result []cluster.Node
+		err    error
+	)
+
+	// Cordoned nodes are rejected.
+	result, err = f.Filter(&dockerclient.ContainerConfig{}, nodes)
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, result[0], nodes[0])
+
+	// Fail if all the nodes are cordoned.
+	result, err = f.Filter(&dockerclient.ContainerConfig{}, nodes[1:])
+	assert.Error(t, err)
+}
@@ -12,6 +12,10 @@ import (
 type DependencyFilter struct {
 }
 
+func (f *DependencyFilter) Name() string {
+	return "dependency"
+}
+
 func (f *DependencyFilter) Filter(config *dockerclient.ContainerConfig, nodes []cluster.Node) ([]cluster.Node, error) {
 	if len(nodes) == 0 {
 		return nodes, nil
@@ -3,6 +3,7 @@ package filter
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	log "github.com/Sirupsen/logrus"
@@ -11,14 +12,46 @@ import (
 const (
 	EQ = iota
 	NOTEQ
+	GTE
+	LTE
+	GT
+	LT
 )
 
-var OPERATORS = []string{"==", "!="}
+var OPERATORS = []string{"==", "!=", ">=", "<=", ">", "<"}
 
 type expr struct {
 	key      string
 	operator int
 	value    string
+	// Soft expressions, whose value starts with `~`, are only preferred:
+	// when no node matches, the filter keeps every candidate instead of failing.
+	isSoft bool
+}
+
+// Format the expression as written in the env.
+func (e *expr) String() string {
+	soft := ""
+	if e.isSoft {
+		soft = "~"
+	}
+	return e.key + OPERATORS[e.operator] + soft + e.value
+}
+
+// Return the index in OPERATORS of the first operator found in `entry`,
+// preferring the longest one when several start at the same position, or -1.
+func findOperator(entry string) int {
+	found, position := -1, -1
+	for i, op := range OPERATORS {
+		p := strings.Index(entry, op)
+		if p < 0 {
+			continue
+		}
+		if found < 0 || p < position || (p == position && len(op) > len(OPERATORS[found])) {
+			found, position = i, p
+		}
+	}
+	return found
 }
 
 func parseExprs(key string, env []string) ([]expr, error) {
@@ -26,52 +59,114 @@ func parseExprs(key string, env []string) ([]expr, error) {
 	for _, e := range env {
 		if strings.HasPrefix(e, key+":") {
 			entry := strings.TrimPrefix(e, key+":")
-			found := false
-			for i, op := range OPERATORS {
-				if strings.Contains(entry, op) {
-					// split with the op
-					parts := strings.SplitN(entry, op, 2)
-
-					// validate key
-					// allow alpha-numeric
-					matched, err := regexp.MatchString(`^(?i)[a-z_][a-z0-9\-_]+$`, parts[0])
-					if err != nil {
-						return nil, err
-					}
-					if matched == false {
-						return nil, fmt.Errorf("Key '%s' is invalid", parts[0])
-					}
-
-					if len(parts) == 2 {
-
-						// validate value
-						// allow leading = in case of using ==
-						// allow * for globbing
-						// allow regexp
-						matched, err := regexp.MatchString(`^(?i)[=!\/]?[a-z0-9:\-_\.\*/\(\)\?\+\[\]\\\^\$]+$`, parts[1])
-						if err != nil {
-							return nil, err
-						}
-						if matched == false {
-							return nil, fmt.Errorf("Value '%s' is invalid", parts[1])
-						}
-						exprs = append(exprs, expr{key: strings.ToLower(parts[0]), operator: i, value: parts[1]})
-					} else {
-						exprs = append(exprs, expr{key: strings.ToLower(parts[0]), operator: i})
-					}
-
-					found = true
-					break // found an op, move to next entry
-				}
+			i := findOperator(entry)
+			if i < 0 {
+				return nil, fmt.Errorf("One of operator %s is expected", strings.Join(OPERATORS, ", "))
+			}
+
+			// split with the op
+			parts := strings.SplitN(entry, OPERATORS[i], 2)
+
+			// validate key
+			// allow alpha-numeric
+			matched, err := regexp.MatchString(`^(?i)[a-z_][a-z0-9\-_]+$`, parts[0])
+			if err != nil {
+				return nil, err
+			}
+			if matched == false {
+				return nil, fmt.Errorf("Key '%s' is invalid", parts[0])
 			}
-			if !found {
-				return nil, fmt.Errorf("One of operator ==, != is expected")
+
+			if len(parts) == 2 {
+				isSoft := strings.HasPrefix(parts[1], "~")
+				if isSoft {
+					parts[1] = strings.TrimPrefix(parts[1], "~")
+				}
+
+				// validate value
+				// allow leading = in case of using ==
+				// allow * for globbing
+				// allow regexp
+				matched, err := regexp.MatchString(`^(?i)[=!\/]?[a-z0-9:\-_\.\*/\(\)\?\+\[\]\\\^\$]+$`, parts[1])
+				if err != nil {
+					return nil, err
+				}
+				if matched == false {
+					return nil, fmt.Errorf("Value '%s' is invalid", parts[1])
+				}
+				exprs = append(exprs, expr{key: strings.ToLower(parts[0]), operator: i, value: parts[1], isSoft: isSoft})
+			} else {
+				exprs = append(exprs, expr{key: strings.ToLower(parts[0]), operator: i})
 			}
 		}
 	}
 	return exprs, nil
 }
 
+// Compare `a` and `b` as numbers when they both are plain numbers, and as
+// versions otherwise: dotted values such as `3.9` and `3.18` are compared
+// segment by segment, the numeric segments as numbers and the other ones as
+// strings, and a missing segment counts as `0` so that `3.2` equals `3.2.0`.
+// Return -1, 0 or 1.
+func compare(a, b string) int {
+	if !strings.Contains(a, ".") && !strings.Contains(b, ".") {
+		if x, err := strconv.ParseFloat(a, 64); err == nil {
+			if y, err := strconv.ParseFloat(b, 64); err == nil {
+				switch {
+				case x < y:
+					return -1
+				case x > y:
+					return 1
+				}
+				return 0
+			}
+		}
+	}
+
+	split := func(r rune) bool { return r == '.' || r == '-' || r == '_' || r == '+' }
+	as, bs := strings.FieldsFunc(a, split), strings.FieldsFunc(b, split)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		sa, sb := "0", "0"
+		if i < len(as) {
+			sa = as[i]
+		}
+		if i < len(bs) {
+			sb = bs[i]
+		}
+		x, errx := strconv.ParseInt(sa, 10, 64)
+		y, erry := strconv.ParseInt(sb, 10, 64)
+		switch {
+		case errx == nil && erry == nil && x < y:
+			return -1
+		case errx == nil && erry == nil && x > y:
+			return 1
+		case (errx != nil || erry != nil) && sa < sb:
+			return -1
+		case (errx != nil || erry != nil) && sa > sb:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Return whether any of `whats` compares to the value as the operator wants.
+func (e *expr) compare(whats ...string) bool {
+	for _, what := range whats {
+		if what == "" {
+			continue
+		}
+		c := compare(what, e.value)
+		switch {
+		case e.operator == GTE && c >= 0,
+			e.operator == LTE && c <= 0,
+			e.operator == GT && c > 0,
+			e.operator == LT && c < 0:
+			return true
+		}
+	}
+	return false
+}
+
 func (e *expr) Match(whats ...string) bool {
 	var (
 		pattern string
@@ -79,6 +174,11 @@ func (e *expr) Match(whats ...string) bool {
 		err     error
 	)
 
+	switch e.operator {
+	case GTE, LTE, GT, LT:
+		return e.compare(whats...)
+	}
+
 	if e.value[0] == '/' && e.value[len(e.value)-1] == '/' {
 		// regexp
 		pattern = e.value[1 : len(e.value)-1]
@@ -34,6 +34,55 @@ func TestParseExprs(t *testing.T) {
 	// Allow regexp in value
 	_, err = parseExprs("constraint", []string{"constraint:node==/(?i)^[a-b]+c*$/"})
 	assert.NoError(t, err)
+
+	// Soft expressions start their value with ~
+	exprs, err := parseExprs("constraint", []string{"constraint:node==~node1", "constraint:zone!=~us-*", "constraint:group==1"})
+	assert.NoError(t, err)
+	assert.Len(t, exprs, 3)
+	assert.Equal(t, exprs[0], expr{key: "node", operator: EQ, value: "node1", isSoft: true})
+	assert.Equal(t, exprs[1], expr{key: "zone", operator: NOTEQ, value: "us-*", isSoft: true})
+	assert.Equal(t, exprs[2], expr{key: "group", operator: EQ, value: "1"})
+	assert.Equal(t, exprs[0].String(), "node==~node1")
+
+	// Only one ~ is allowed
+	_, err = parseExprs("constraint", []string{"constraint:node==~~node1"})
+	assert.Error(t, err)
+}
+
+func TestParseComparisonExprs(t *testing.T) {
+	exprs, err := parseExprs("constraint", []string{
+		"constraint:memory_gb>=64",
+		"constraint:kernel<=3.18",
+		"constraint:disks>2",
+		"constraint:load<~0.5",
+	})
+	assert.NoError(t, err)
+	assert.Len(t, exprs, 4)
+	assert.Equal(t, exprs[0], expr{key: "memory_gb", operator: GTE, value: "64"})
+	assert.Equal(t, exprs[1], expr{key: "kernel", operator: LTE, value: "3.18"})
+	assert.Equal(t, exprs[2], expr{key: "disks", operator: GT, value: "2"})
+	assert.Equal(t, exprs[3], expr{key: "load", operator: LT, value: "0.5", isSoft: true})
+
+	_, err = parseExprs("constraint", []string{"constraint:memory_gb=>64"})
+	assert.Error(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	// Numbers
+	assert.Equal(t, compare("500", "64"), 1)
+	assert.Equal(t, compare("64", "64.0"), 0)
+	assert.Equal(t, compare("-1", "2"), -1)
+
+	// Versions
+	assert.Equal(t, compare("3.16.0-4-amd64", "3.18"), -1)
+	assert.Equal(t, compare("3.18.0", "3.18"), 0)
+	assert.Equal(t, compare("3.9", "3.18"), -1)
+	assert.Equal(t, compare("3.18", "3.9"), 1)
+	assert.Equal(t, compare("3.2", "3.2.0"), 0)
+	assert.Equal(t, compare("3.2.1", "3.2"), 1)
+	assert.Equal(t, compare("4.1.2", "4.1.2"), 0)
+	assert.Equal(t, compare("1.10.0", "1.9.1"), 1)
+	assert.Equal(t, compare("1.6.0-rc1", "1.6.0-rc2"), -1)
 }
 
 func TestMatch(t *testing.T) {
@@ -58,3 +107,25 @@ func TestMatch(t *testing.T) {
 	assert.False(t, e.Match("fuo"))
 	assert.False(t, e.Match("foo", "fuo", "bar"))
 }
+
+func TestMatchComparison(t *testing.T) {
+	e := expr{operator: GTE, value: "64"}
+	assert.True(t, e.Match("64"))
+	assert.True(t, e.Match("128"))
+	assert.False(t, e.Match("32"))
+	assert.False(t, e.Match(""))
+	assert.True(t, e.Match("32", "128"))
+
+	e = expr{operator: GT, value: "64"}
+	assert.False(t, e.Match("64"))
+	assert.True(t, e.Match("65"))
+
+	e = expr{operator: LTE, value: "3.18"}
+	assert.True(t, e.Match("3.16.0-4-amd64"))
+	assert.True(t, e.Match("3.18"))
+	assert.False(t, e.Match("3.19.1"))
+
+	e = expr{operator: LT, value: "3.18"}
+	assert.False(t, e.Match("3.18"))
+	assert.True(t, e.Match("3.2.0"))
+}
@@ -9,6 +9,7 @@ type FakeNode struct {
 	containers []*cluster.Container
 	images     []*cluster.Image
 	labels     map[string]string
+	cordoned   bool
 }
 
 func (fn *FakeNode) ID() string               { return fn.id }
@@ -39,6 +40,7 @@ func (fn *FakeNode) TotalMemory() int64        { return 0 }
 func (fn *FakeNode) UsedMemory() int64         { return 0 }
 func (fn *FakeNode) Labels() map[string]string { return fn.labels }
 func (fn *FakeNode) IsHealthy() bool           { return true }
+func (fn *FakeNode) IsCordoned() bool          { return fn.cordoned }
 
 func (fn *FakeNode) AddContainer(container *cluster.Container) error {
 	fn.containers = append(fn.containers, container)
@@ -9,10 +9,21 @@ import (
 )
 
 type Filter interface {
+	Name() string
+
 	// Return a subset of nodes that were accepted by the filtering policy.
 	Filter(*dockerclient.ContainerConfig, []cluster.Node) ([]cluster.Node, error)
 }
 
+// A filter whose decision depends on every node of the cluster, not only on
+// the candidates left by the previous filters.
+type ClusterFilter interface {
+	Filter
+
+	// Return the subset of `candidates` accepted, out of the nodes `all`.
+	FilterCandidates(config *dockerclient.ContainerConfig, all, candidates []cluster.Node) ([]cluster.Node, error)
+}
+
 var (
 	filters         map[string]Filter
 	ErrNotSupported = errors.New("filter not supported")
@@ -22,9 +33,11 @@ func init() {
 	filters = map[string]Filter{
 		"affinity":   &AffinityFilter{},
 		"health":     &HealthFilter{},
+		"cordon":     &CordonFilter{},
 		"constraint": &ConstraintFilter{},
 		"port":       &PortFilter{},
 		"dependency": &DependencyFilter{},
+		"topology":   &TopologyFilter{},
 	}
 }
 
@@ -42,12 +55,23 @@ func New(names []string) ([]Filter, error) {
 	return selectedFilters, nil
 }
 
+// Apply `f` to `candidates`, the nodes of `all` accepted so far.
+func Apply(f Filter, config *dockerclient.ContainerConfig, all, candidates []cluster.Node) ([]cluster.Node, error) {
+	if cf, ok := f.(ClusterFilter); ok {
+		return cf.FilterCandidates(config, all, candidates)
+	}
+	return f.Filter(config, candidates)
+}
+
 // Apply a set of filters in batch.
 func ApplyFilters(filters []Filter, config *dockerclient.ContainerConfig, nodes []cluster.Node) ([]cluster.Node, error) {
-	var err error
+	var (
+		err error
+		all = nodes
+	)
 
 	for _, filter := range filters {
-		nodes, err = filter.Filter(config, nodes)
+		nodes, err = Apply(filter, config, all, nodes)
 		if err != nil {
 			return nil, err
 		}
@@ -15,6 +15,10 @@ var (
 type HealthFilter struct {
 }
 
+func (f *HealthFilter) Name() string {
+	return "health"
+}
+
 func (f *HealthFilter) Filter(_ *dockerclient.ContainerConfig, nodes []cluster.Node) ([]cluster.Node, error) {
 	result := []cluster.Node{}
 	for _, node := range nodes {
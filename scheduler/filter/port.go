@@ -13,6 +13,10 @@ import (
 type PortFilter struct {
 }
 
+func (p *PortFilter) Name() string {
+	return "port"
+}
+
 func (p *PortFilter) Filter(config *dockerclient.ContainerConfig, nodes []cluster.Node) ([]cluster.Node, error) {
 	if config.HostConfig.NetworkMode == "host" {
 		for port := range config.ExposedPorts {
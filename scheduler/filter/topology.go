@@ -0,0 +1,125 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+)
+
+// TopologyFilter spreads the replicas of a container across the values of a
+// node label, such as `spread:rack`. The replicas are the containers created
+// with the same `group:<name>` env hint or, without it, from the same image.
+type TopologyFilter struct {
+}
+
+// A label to spread the replicas over. Soft spreads, written `spread:~rack`,
+// are ignored rather than failing when no node has the label.
+type spread struct {
+	key    string
+	isSoft bool
+}
+
+func (f *TopologyFilter) Name() string {
+	return "topology"
+}
+
+func parseSpreads(env []string) ([]spread, error) {
+	spreads := []spread{}
+	for _, e := range env {
+		if !strings.HasPrefix(e, "spread:") {
+			continue
+		}
+		key := strings.TrimPrefix(e, "spread:")
+		isSoft := strings.HasPrefix(key, "~")
+		key = strings.TrimPrefix(key, "~")
+
+		matched, err := regexp.MatchString(`^(?i)[a-z_][a-z0-9\-_]+$`, key)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			return nil, fmt.Errorf("Key '%s' is invalid", key)
+		}
+		spreads = append(spreads, spread{key: strings.ToLower(key), isSoft: isSoft})
+	}
+	return spreads, nil
+}
+
+// Return the `group:<name>` env hint of `config`, if any.
+func group(config *dockerclient.ContainerConfig) string {
+	for _, env := range config.Env {
+		if strings.HasPrefix(env, "group:") {
+			return strings.TrimPrefix(env, "group:")
+		}
+	}
+	return ""
+}
+
+// Return whether `container` is a replica of the container to create.
+func isReplica(config *dockerclient.ContainerConfig, container *cluster.Container) bool {
+	if container.Info.Config == nil {
+		return group(config) == "" && container.Image == config.Image
+	}
+	if name := group(config); name != "" {
+		return group(container.Info.Config) == name
+	}
+	return container.Info.Config.Image == config.Image
+}
+
+func (f *TopologyFilter) Filter(config *dockerclient.ContainerConfig, nodes []cluster.Node) ([]cluster.Node, error) {
+	return f.FilterCandidates(config, nodes, nodes)
+}
+
+// The replicas are counted over every node, including the ones dropped by the
+// previous filters, and the candidates of the least populated domains are
+// kept.
+func (f *TopologyFilter) FilterCandidates(config *dockerclient.ContainerConfig, all, nodes []cluster.Node) ([]cluster.Node, error) {
+	spreads, err := parseSpreads(config.Env)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range spreads {
+		// Count the replicas of every value of the label.
+		replicas := make(map[string]int)
+		for _, node := range all {
+			value, ok := node.Labels()[s.key]
+			if !ok {
+				continue
+			}
+			for _, container := range node.Containers() {
+				if isReplica(config, container) {
+					replicas[value]++
+				}
+			}
+		}
+
+		least := -1
+		for _, node := range nodes {
+			if value, ok := node.Labels()[s.key]; ok && (least < 0 || replicas[value] < least) {
+				least = replicas[value]
+			}
+		}
+
+		if least < 0 {
+			if s.isSoft {
+				log.Debugf("no node has the label %s to spread over, ignoring it", s.key)
+				continue
+			}
+			return nil, fmt.Errorf("unable to find a node with the label %s to spread over", s.key)
+		}
+
+		candidates := []cluster.Node{}
+		for _, node := range nodes {
+			if value, ok := node.Labels()[s.key]; ok && replicas[value] == least {
+				candidates = append(candidates, node)
+			}
+		}
+		nodes = candidates
+	}
+	return nodes, nil
+}
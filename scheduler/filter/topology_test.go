@@ -0,0 +1,87 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func replica(image string, env ...string) *cluster.Container {
+	return &cluster.Container{Info: dockerclient.ContainerInfo{Config: &dockerclient.ContainerConfig{Image: image, Env: env}}}
+}
+
+func TestTopologyFilter(t *testing.T) {
+	var (
+		f     = TopologyFilter{}
+		nodes = []cluster.Node{
+			&FakeNode{
+				id:         "node-0-id",
+				labels:     map[string]string{"rack": "r1", "zone": "a"},
+				containers: []*cluster.Container{replica("redis"), replica("nginx", "group:web")},
+			},
+			&FakeNode{
+				id:         "node-1-id",
+				labels:     map[string]string{"rack": "r1", "zone": "b"},
+				containers: []*cluster.Container{replica("redis")},
+			},
+			&FakeNode{
+				id:     "node-2-id",
+				labels: map[string]string{"rack": "r2", "zone": "a"},
+			},
+			&FakeNode{
+				id: "node-3-id",
+			},
+		}
+		result []cluster.Node
+		err    error
+	)
+
+	// Without spread we should get the unfiltered list of nodes back.
+	result, err = f.Filter(&dockerclient.ContainerConfig{Image: "redis"}, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes)
+
+	// The replicas of an image go to the rack holding the fewest of them.
+	result, err = f.Filter(&dockerclient.ContainerConfig{Image: "redis", Env: []string{"spread:rack"}}, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, result, []cluster.Node{nodes[2]})
+
+	// Every node of the least populated domains is a candidate.
+	result, err = f.Filter(&dockerclient.ContainerConfig{Image: "mysql", Env: []string{"spread:rack"}}, nodes)
+	assert.NoError(t, err)
+	assert.Len(t, result, 3)
+	assert.NotContains(t, result, nodes[3])
+
+	// Replicas can be grouped regardless of their image.
+	result, err = f.Filter(&dockerclient.ContainerConfig{Image: "apache", Env: []string{"group:web", "spread:zone"}}, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, result, []cluster.Node{nodes[1]})
+
+	// The replicas on the nodes ruled out by the previous filters count too.
+	result, err = f.FilterCandidates(&dockerclient.ContainerConfig{Image: "apache", Env: []string{"group:web", "spread:zone"}}, nodes, []cluster.Node{nodes[1], nodes[2]})
+	assert.NoError(t, err)
+	assert.Equal(t, result, []cluster.Node{nodes[1]})
+
+	// Spreads can be chained.
+	result, err = f.Filter(&dockerclient.ContainerConfig{Image: "mysql", Env: []string{"spread:rack", "spread:zone"}}, nodes)
+	assert.NoError(t, err)
+	assert.Len(t, result, 3)
+
+	// A hard spread fails when no node has the label, a soft one is ignored.
+	result, err = f.Filter(&dockerclient.ContainerConfig{Image: "redis", Env: []string{"spread:region"}}, nodes)
+	assert.Error(t, err)
+
+	result, err = f.Filter(&dockerclient.ContainerConfig{Image: "redis", Env: []string{"spread:~region"}}, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, result, nodes)
+
+	result, err = f.Filter(&dockerclient.ContainerConfig{Image: "redis", Env: []string{"spread:~rack"}}, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, result, []cluster.Node{nodes[2]})
+
+	// Keys are validated like constraints.
+	result, err = f.Filter(&dockerclient.ContainerConfig{Env: []string{"spread:ra ck"}}, nodes)
+	assert.Error(t, err)
+}
@@ -1,15 +1,27 @@
 package scheduler
 
 import (
+	"time"
+
 	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/metrics"
 	"github.com/docker/swarm/scheduler/filter"
 	"github.com/docker/swarm/scheduler/strategy"
 	"github.com/samalba/dockerclient"
 )
 
+var (
+	attemptsMetric = metrics.NewCounter("swarm_scheduler_attempts_total", "Number of times a filter or strategy was applied.", "stage", "name")
+	failuresMetric = metrics.NewCounter("swarm_scheduler_failures_total", "Number of times a filter or strategy failed.", "stage", "name")
+	latencyMetric  = metrics.NewHistogram("swarm_scheduler_latency_seconds", "Time spent applying a filter or strategy.", metrics.DefBuckets, "stage", "name")
+)
+
 type Scheduler struct {
 	strategy strategy.PlacementStrategy
 	filters  []filter.Filter
+
+	// Dry runs don't record metrics.
+	dryRun bool
 }
 
 func New(strategy strategy.PlacementStrategy, filters []filter.Filter) *Scheduler {
@@ -19,12 +31,140 @@ func New(strategy strategy.PlacementStrategy, filters []filter.Filter) *Schedule
 	}
 }
 
+// Return a scheduler making the same decisions, without recording them in the
+// metrics: for the decisions only explained or planned, not acted upon.
+func (s *Scheduler) DryRun() *Scheduler {
+	return &Scheduler{strategy: s.strategy, filters: s.filters, dryRun: true}
+}
+
+// Record an attempt of the `stage` ("filter" or "strategy") called `name`.
+func (s *Scheduler) observe(stage, name string, start time.Time, err error) {
+	if s.dryRun {
+		return
+	}
+	attemptsMetric.Inc(stage, name)
+	latencyMetric.Since(start, stage, name)
+	if err != nil {
+		failuresMetric.Inc(stage, name)
+	}
+}
+
+func names(nodes []cluster.Node) []string {
+	names := []string{}
+	for _, n := range nodes {
+		names = append(names, n.Name())
+	}
+	return names
+}
+
+// Find out why `f` rejected `n`, by applying it to this node only.
+func rejection(f filter.Filter, config *dockerclient.ContainerConfig, all []cluster.Node, n cluster.Node) string {
+	if _, err := filter.Apply(f, config, all, []cluster.Node{n}); err != nil {
+		return err.Error()
+	}
+	return "not accepted along with the other candidates"
+}
+
 // Find a nice home for our container.
 func (s *Scheduler) SelectNodeForContainer(nodes []cluster.Node, config *dockerclient.ContainerConfig) (cluster.Node, error) {
-	accepted, err := filter.ApplyFilters(s.filters, config, nodes)
+	node, _, err := s.Schedule(nodes, config)
+	return node, err
+}
+
+// Find all the nodes accepted by the filters, without applying the strategy.
+func (s *Scheduler) FilterNodes(nodes []cluster.Node, config *dockerclient.ContainerConfig) ([]cluster.Node, error) {
+	accepted, _, err := s.Filter(nodes, config)
+	return accepted, err
+}
+
+// Like SelectNodeForContainer, also returning the trace of the decision.
+func (s *Scheduler) Schedule(nodes []cluster.Node, config *dockerclient.ContainerConfig) (cluster.Node, *cluster.Decision, error) {
+	accepted, decision, err := s.Filter(nodes, config)
+	if err != nil {
+		return nil, decision, err
+	}
+
+	decision.Strategy = s.strategy.Name()
+	if w, ok := s.strategy.(strategy.WeighingStrategy); ok {
+		decision.Scores = make(map[string]int64)
+		for n, weight := range w.Weigh(config, accepted) {
+			decision.Scores[n.Name()] = weight
+		}
+	}
+
+	start := time.Now()
+	node, err := s.strategy.PlaceContainer(config, accepted)
+	s.observe("strategy", s.strategy.Name(), start, err)
 	if err != nil {
-		return nil, err
+		decision.Error = err.Error()
+		decision.Explain()
+		return nil, decision, err
+	}
+	decision.Node = node.Name()
+	return node, decision, nil
+}
+
+// Like FilterNodes, also returning the trace of the decision. Why the nodes
+// were rejected is only worked out on failures and dry runs, or later on by
+// Decision.Explain.
+func (s *Scheduler) Filter(nodes []cluster.Node, config *dockerclient.ContainerConfig) ([]cluster.Node, *cluster.Decision, error) {
+	type rejections struct {
+		step  *cluster.FilterDecision
+		f     filter.Filter
+		nodes []cluster.Node
 	}
 
-	return s.strategy.PlaceContainer(config, accepted)
+	var (
+		decision = &cluster.Decision{Candidates: names(nodes)}
+		all      = nodes
+		pending  = []rejections{}
+	)
+	decision.ExplainWith(func() {
+		for _, r := range pending {
+			r.step.Rejected = make(map[string]string)
+			for _, n := range r.nodes {
+				r.step.Rejected[n.Name()] = rejection(r.f, config, all, n)
+			}
+		}
+	})
+
+	for _, f := range s.filters {
+		start := time.Now()
+		accepted, err := filter.Apply(f, config, all, nodes)
+		s.observe("filter", f.Name(), start, err)
+
+		step := &cluster.FilterDecision{Name: f.Name(), Accepted: names(accepted)}
+		rejected := []cluster.Node{}
+		for _, n := range nodes {
+			if !contains(accepted, n) {
+				rejected = append(rejected, n)
+			}
+		}
+		if len(rejected) > 0 {
+			pending = append(pending, rejections{step: step, f: f, nodes: rejected})
+		}
+		decision.Filters = append(decision.Filters, step)
+
+		if err != nil {
+			step.Error = err.Error()
+			decision.Error = err.Error()
+			decision.Explain()
+			return nil, decision, err
+		}
+		nodes = accepted
+	}
+
+	if s.dryRun {
+		decision.Explain()
+	}
+	return nodes, decision, nil
+}
+
+func contains(nodes []cluster.Node, n cluster.Node) bool {
+	for _, node := range nodes {
+		if node == n {
+			return true
+		}
+	}
+	return false
 }
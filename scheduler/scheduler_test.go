@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/docker/swarm/scheduler/filter"
+	"github.com/docker/swarm/scheduler/strategy"
+	"github.com/samalba/dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+type namedNode struct {
+	cluster.Node
+	name string
+}
+
+func (n *namedNode) Name() string { return n.name }
+
+// A filter rejecting the nodes named in `rejected`, counting its runs.
+type countingFilter struct {
+	rejected map[string]bool
+	runs     int
+}
+
+func (f *countingFilter) Name() string { return "counting" }
+
+func (f *countingFilter) Filter(config *dockerclient.ContainerConfig, nodes []cluster.Node) ([]cluster.Node, error) {
+	f.runs++
+	accepted := []cluster.Node{}
+	for _, n := range nodes {
+		if !f.rejected[n.Name()] {
+			accepted = append(accepted, n)
+		}
+	}
+	if len(accepted) == 0 {
+		return nil, errors.New("no node left")
+	}
+	return accepted, nil
+}
+
+func TestScheduleRejections(t *testing.T) {
+	var (
+		f     = &countingFilter{rejected: map[string]bool{"node-1": true}}
+		s     = New(&strategy.RandomPlacementStrategy{}, []filter.Filter{f})
+		nodes = []cluster.Node{&namedNode{name: "node-1"}, &namedNode{name: "node-2"}}
+	)
+
+	// A successful placement runs the filter once, and explains the
+	// rejections only when asked to.
+	node, decision, err := s.Schedule(nodes, &dockerclient.ContainerConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, node.Name(), "node-2")
+	assert.Equal(t, f.runs, 1)
+	assert.Nil(t, decision.Filters[0].Rejected)
+
+	decision.Explain()
+	assert.Equal(t, f.runs, 2)
+	assert.Equal(t, decision.Filters[0].Rejected["node-1"], "no node left")
+	decision.Explain()
+	assert.Equal(t, f.runs, 2)
+
+	// Dry runs explain them right away.
+	f.runs = 0
+	_, decision, err = s.DryRun().Schedule(nodes, &dockerclient.ContainerConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, f.runs, 2)
+	assert.Equal(t, decision.Filters[0].Rejected["node-1"], "no node left")
+
+	// So do failures.
+	f.runs = 0
+	f.rejected["node-2"] = true
+	_, decision, err = s.Schedule(nodes, &dockerclient.ContainerConfig{})
+	assert.Error(t, err)
+	assert.Equal(t, f.runs, 3)
+	assert.Equal(t, len(decision.Filters[0].Rejected), 2)
+}
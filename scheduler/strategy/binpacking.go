@@ -14,11 +14,32 @@ var (
 
 type BinPackingPlacementStrategy struct{}
 
+func (p *BinPackingPlacementStrategy) Name() string {
+	return "binpacking"
+}
+
 func (p *BinPackingPlacementStrategy) Initialize() error {
 	return nil
 }
 
 func (p *BinPackingPlacementStrategy) PlaceContainer(config *dockerclient.ContainerConfig, nodes []cluster.Node) (cluster.Node, error) {
+	weightedNodes := p.weigh(config, nodes)
+	if len(weightedNodes) == 0 {
+		return nil, ErrNoResourcesAvailable
+	}
+
+	// sort by highest weight
+	sort.Sort(sort.Reverse(weightedNodes))
+
+	return weightedNodes[0].Node, nil
+}
+
+// Return the weight of every node with enough resources for `config`.
+func (p *BinPackingPlacementStrategy) Weigh(config *dockerclient.ContainerConfig, nodes []cluster.Node) map[cluster.Node]int64 {
+	return p.weigh(config, nodes).weights()
+}
+
+func (p *BinPackingPlacementStrategy) weigh(config *dockerclient.ContainerConfig, nodes []cluster.Node) weightedNodeList {
 	weightedNodes := weightedNodeList{}
 
 	for _, node := range nodes {
@@ -47,12 +68,5 @@ func (p *BinPackingPlacementStrategy) PlaceContainer(config *dockerclient.Contai
 		}
 	}
 
-	if len(weightedNodes) == 0 {
-		return nil, ErrNoResourcesAvailable
-	}
-
-	// sort by highest weight
-	sort.Sort(sort.Reverse(weightedNodes))
-
-	return weightedNodes[0].Node, nil
+	return weightedNodes
 }
@@ -0,0 +1,162 @@
+package strategy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+)
+
+const (
+	// Default time to wait for the external scheduler to answer.
+	defaultExternalTimeout = 5 * time.Second
+
+	// Default strategy used when the external scheduler can't be reached.
+	defaultExternalFallback = "binpacking"
+)
+
+// ExternalPlacementStrategy delegates the placement to an external HTTP
+// endpoint. The endpoint receives the container configuration along with a
+// snapshot of the candidate nodes, and answers with a list of node IDs ranked
+// by preference.
+type ExternalPlacementStrategy struct {
+	url      string
+	timeout  time.Duration
+	fallback PlacementStrategy
+	client   *http.Client
+}
+
+// Snapshot of a node sent to the external scheduler.
+type externalNode struct {
+	ID          string
+	Name        string
+	Labels      map[string]string
+	TotalCpus   int64
+	UsedCpus    int64
+	TotalMemory int64
+	UsedMemory  int64
+	Containers  []string
+	Images      []string
+}
+
+type externalRequest struct {
+	Config *dockerclient.ContainerConfig
+	Nodes  []*externalNode
+}
+
+// Parse `external://host:port/path?timeout=5s&fallback=random`.
+func newExternalPlacementStrategy(rawurl string) (*ExternalPlacementStrategy, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid external strategy %q, missing <host>", rawurl)
+	}
+
+	s := &ExternalPlacementStrategy{timeout: defaultExternalTimeout}
+
+	if timeout := u.Query().Get("timeout"); timeout != "" {
+		if s.timeout, err = time.ParseDuration(timeout); err != nil {
+			return nil, err
+		}
+	}
+
+	fallback := u.Query().Get("fallback")
+	if fallback == "" {
+		fallback = defaultExternalFallback
+	}
+	if s.fallback, err = New(fallback); err != nil {
+		return nil, err
+	}
+
+	u.Scheme = "http"
+	u.RawQuery = ""
+	s.url = u.String()
+	return s, nil
+}
+
+func (p *ExternalPlacementStrategy) Name() string {
+	return "external"
+}
+
+func (p *ExternalPlacementStrategy) Initialize() error {
+	p.client = &http.Client{Timeout: p.timeout}
+	return nil
+}
+
+func (p *ExternalPlacementStrategy) PlaceContainer(config *dockerclient.ContainerConfig, nodes []cluster.Node) (cluster.Node, error) {
+	ranking, err := p.rank(config, nodes)
+	if err != nil {
+		log.WithField("url", p.url).Warnf("External scheduler failed, using fallback strategy: %v", err)
+		return p.fallback.PlaceContainer(config, nodes)
+	}
+
+	for _, id := range ranking {
+		for _, node := range nodes {
+			if node.ID() == id {
+				return node, nil
+			}
+		}
+	}
+	return nil, ErrNoResourcesAvailable
+}
+
+// Ask the external scheduler to rank the nodes.
+func (p *ExternalPlacementStrategy) rank(config *dockerclient.ContainerConfig, nodes []cluster.Node) ([]string, error) {
+	request := &externalRequest{Config: config}
+	for _, node := range nodes {
+		request.Nodes = append(request.Nodes, snapshotNode(node))
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Post(p.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("external scheduler returned %d HTTP status code", resp.StatusCode)
+	}
+
+	ranking := []string{}
+	if err := json.NewDecoder(resp.Body).Decode(&ranking); err != nil {
+		return nil, err
+	}
+	return ranking, nil
+}
+
+func snapshotNode(node cluster.Node) *externalNode {
+	n := &externalNode{
+		ID:          node.ID(),
+		Name:        node.Name(),
+		Labels:      node.Labels(),
+		TotalCpus:   node.TotalCpus(),
+		UsedCpus:    node.UsedCpus(),
+		TotalMemory: node.TotalMemory(),
+		UsedMemory:  node.UsedMemory(),
+		Containers:  []string{},
+		Images:      []string{},
+	}
+	for _, container := range node.Containers() {
+		for _, name := range container.Names {
+			n.Containers = append(n.Containers, strings.TrimPrefix(name, "/"))
+		}
+	}
+	for _, image := range node.Images() {
+		n.Images = append(n.Images, image.RepoTags...)
+	}
+	return n
+}
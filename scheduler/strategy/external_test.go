@@ -0,0 +1,97 @@
+package strategy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func externalURL(server *httptest.Server, query string) string {
+	return "external://" + strings.TrimPrefix(server.URL, "http://") + "/place" + query
+}
+
+func TestExternalPlaceContainer(t *testing.T) {
+	var received externalRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/place")
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		json.NewEncoder(w).Encode([]string{"unknown", "node-1", "node-0"})
+	}))
+	defer server.Close()
+
+	s, err := New(externalURL(server, ""))
+	assert.NoError(t, err)
+
+	nodes := []cluster.Node{createNode("node-0", 2, 2), createNode("node-1", 2, 2)}
+	node, err := s.PlaceContainer(createConfig(1, 1), nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, node.ID(), "node-1")
+
+	// The external scheduler gets the candidates along with the config.
+	assert.Equal(t, received.Config.CpuShares, 1)
+	assert.Len(t, received.Nodes, 2)
+	assert.Equal(t, received.Nodes[0].ID, "node-0")
+	assert.Equal(t, received.Nodes[0].TotalCpus, 2)
+}
+
+func TestExternalNoCandidate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]string{})
+	}))
+	defer server.Close()
+
+	s, err := New(externalURL(server, ""))
+	assert.NoError(t, err)
+
+	_, err = s.PlaceContainer(createConfig(1, 1), []cluster.Node{createNode("node-0", 2, 2)})
+	assert.Equal(t, err, ErrNoResourcesAvailable)
+}
+
+func TestExternalFallback(t *testing.T) {
+	// The server times out until `failing` is set, then fails.
+	var failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	s, err := New(externalURL(server, "?timeout=10ms&fallback=binpacking"))
+	assert.NoError(t, err)
+
+	nodes := []cluster.Node{createNode("node-0", 2, 2), createNode("node-1", 2, 2)}
+	assert.NoError(t, AddContainer(nodes[1], createContainer("c1", createConfig(1, 1))))
+
+	// The external scheduler times out, binpacking picks the fullest node.
+	node, err := s.PlaceContainer(createConfig(1, 1), nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, node.ID(), "node-1")
+
+	// Errors are handled the same way.
+	atomic.StoreInt32(&failing, 1)
+	node, err = s.PlaceContainer(createConfig(1, 1), nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, node.ID(), "node-1")
+}
+
+func TestExternalInvalid(t *testing.T) {
+	_, err := New("external://")
+	assert.Error(t, err)
+
+	_, err = New("external://localhost:1234/?timeout=abc")
+	assert.Error(t, err)
+
+	_, err = New("external://localhost:1234/?fallback=unknown")
+	assert.Equal(t, err, ErrNotSupported)
+}
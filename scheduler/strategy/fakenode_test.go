@@ -15,6 +15,7 @@ type FakeNode struct {
 	cpus       int64
 	usedcpus   int64
 	containers []*cluster.Container
+	weight     int64
 }
 
 func (fn *FakeNode) ID() string                            { return fn.id }
@@ -31,6 +32,8 @@ func (fn *FakeNode) TotalMemory() int64                    { return fn.memory }
 func (fn *FakeNode) UsedMemory() int64                     { return fn.usedmemory }
 func (fn *FakeNode) Labels() map[string]string             { return nil }
 func (fn *FakeNode) IsHealthy() bool                       { return true }
+func (fn *FakeNode) IsCordoned() bool                      { return false }
+func (fn *FakeNode) Weight() int64                         { return fn.weight }
 
 func (fn *FakeNode) AddContainer(container *cluster.Container) error {
 	memory := container.Info.Config.Memory
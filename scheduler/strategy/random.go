@@ -12,6 +12,10 @@ import (
 // Randomly place the container into the cluster.
 type RandomPlacementStrategy struct{}
 
+func (p *RandomPlacementStrategy) Name() string {
+	return "random"
+}
+
 func (p *RandomPlacementStrategy) Initialize() error {
 	rand.Seed(time.Now().UTC().UnixNano())
 	return nil
@@ -0,0 +1,73 @@
+package strategy
+
+import (
+	"sort"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/samalba/dockerclient"
+)
+
+// SpreadPlacementStrategy places the container on the least reserved node.
+type SpreadPlacementStrategy struct{}
+
+func (p *SpreadPlacementStrategy) Name() string {
+	return "spread"
+}
+
+func (p *SpreadPlacementStrategy) Initialize() error {
+	return nil
+}
+
+func (p *SpreadPlacementStrategy) PlaceContainer(config *dockerclient.ContainerConfig, nodes []cluster.Node) (cluster.Node, error) {
+	weightedNodes := p.weigh(config, nodes)
+	if len(weightedNodes) == 0 {
+		return nil, ErrNoResourcesAvailable
+	}
+
+	// sort by lowest weight, then by fewest containers
+	for _, n := range weightedNodes {
+		n.containers = len(n.Node.Containers())
+	}
+	sort.Sort(spreadOrder{weightedNodes})
+
+	return weightedNodes[0].Node, nil
+}
+
+// Return the weight of every node with enough resources for `config`.
+func (p *SpreadPlacementStrategy) Weigh(config *dockerclient.ContainerConfig, nodes []cluster.Node) map[cluster.Node]int64 {
+	return p.weigh(config, nodes).weights()
+}
+
+func (p *SpreadPlacementStrategy) weigh(config *dockerclient.ContainerConfig, nodes []cluster.Node) weightedNodeList {
+	weightedNodes := weightedNodeList{}
+
+	for _, node := range nodes {
+		nodeMemory := node.TotalMemory()
+		nodeCpus := node.TotalCpus()
+
+		// Skip nodes that are smaller than the requested resources.
+		if nodeMemory < int64(config.Memory) || nodeCpus < config.CpuShares {
+			continue
+		}
+
+		var (
+			cpuScore    int64 = 0
+			memoryScore int64 = 0
+		)
+
+		if nodeCpus > 0 {
+			cpuScore = (node.UsedCpus() + config.CpuShares) * 100 / nodeCpus
+		}
+		if nodeMemory > 0 {
+			memoryScore = (node.UsedMemory() + config.Memory) * 100 / nodeMemory
+		}
+
+		// Heavier nodes look less reserved, to get proportionally more
+		// containers.
+		if cpuScore <= 100 && memoryScore <= 100 {
+			weightedNodes = append(weightedNodes, &weightedNode{Node: node, Weight: (cpuScore + memoryScore) / nodeWeight(node)})
+		}
+	}
+
+	return weightedNodes
+}
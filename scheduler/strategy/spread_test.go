@@ -0,0 +1,114 @@
+package strategy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/docker/swarm/cluster"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpreadPlaceContainerMemory(t *testing.T) {
+	s := &SpreadPlacementStrategy{}
+
+	nodes := []cluster.Node{}
+	for i := 0; i < 2; i++ {
+		nodes = append(nodes, createNode(fmt.Sprintf("node-%d", i), 2, 1))
+	}
+
+	// add 1 container 1G
+	config := createConfig(1, 0)
+	node1, err := s.PlaceContainer(config, nodes)
+	assert.NoError(t, err)
+	assert.NoError(t, AddContainer(node1, createContainer("c1", config)))
+
+	// add another container 1G
+	config = createConfig(1, 0)
+	node2, err := s.PlaceContainer(config, nodes)
+	assert.NoError(t, err)
+	assert.NoError(t, AddContainer(node2, createContainer("c2", config)))
+
+	// check that the containers ended on different nodes
+	assert.NotEqual(t, node1.ID(), node2.ID())
+	assert.Equal(t, len(node1.Containers()), 1)
+	assert.Equal(t, len(node2.Containers()), 1)
+}
+
+func TestSpreadPlaceContainerCPU(t *testing.T) {
+	s := &SpreadPlacementStrategy{}
+
+	nodes := []cluster.Node{}
+	for i := 0; i < 3; i++ {
+		nodes = append(nodes, createNode(fmt.Sprintf("node-%d", i), 1, 4))
+	}
+
+	// add 3 containers 1CPU, one per node
+	for i := 0; i < 3; i++ {
+		node, err := s.PlaceContainer(createConfig(0, 1), nodes)
+		assert.NoError(t, err)
+		assert.NoError(t, AddContainer(node, createContainer(fmt.Sprintf("c%d", i), createConfig(0, 1))))
+	}
+	for _, node := range nodes {
+		assert.Equal(t, len(node.Containers()), 1)
+		assert.Equal(t, node.UsedCpus(), 1)
+	}
+}
+
+func TestSpreadPlaceContainerRatio(t *testing.T) {
+	s := &SpreadPlacementStrategy{}
+
+	// A big node and a small one.
+	nodes := []cluster.Node{createNode("big", 8, 8), createNode("small", 2, 2)}
+
+	// 2 CPUs is a quarter of the big node but all of the small one.
+	assert.NoError(t, AddContainer(nodes[0], createContainer("c1", createConfig(0, 2))))
+
+	// The small node is empty but the next container would fill it.
+	node, err := s.PlaceContainer(createConfig(0, 2), nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, node.ID(), "big")
+
+	// Too big for everyone.
+	_, err = s.PlaceContainer(createConfig(0, 10), nodes)
+	assert.Error(t, err)
+}
+
+func TestSpreadPlaceContainerWeight(t *testing.T) {
+	s := &SpreadPlacementStrategy{}
+
+	// The heavy node gets twice as many containers.
+	heavy := createNode("heavy", 4, 4)
+	heavy.(*FakeNode).weight = 2
+	nodes := []cluster.Node{heavy, createNode("light", 4, 4)}
+
+	for i := 0; i < 3; i++ {
+		node, err := s.PlaceContainer(createConfig(0, 1), nodes)
+		assert.NoError(t, err)
+		assert.NoError(t, AddContainer(node, createContainer(fmt.Sprintf("c%d", i), createConfig(0, 1))))
+	}
+	assert.Equal(t, len(nodes[0].Containers()), 2)
+	assert.Equal(t, len(nodes[1].Containers()), 1)
+}
+
+func TestSpreadPlaceContainerTie(t *testing.T) {
+	s := &SpreadPlacementStrategy{}
+
+	nodes := []cluster.Node{createNode("node-0", 2, 2), createNode("node-1", 2, 2)}
+
+	// Containers without reservations don't change the ratio.
+	assert.NoError(t, AddContainer(nodes[0], createContainer("c1", createConfig(0, 0))))
+	assert.NoError(t, AddContainer(nodes[0], createContainer("c2", createConfig(0, 0))))
+
+	// On a tie, the node with the fewest containers wins.
+	for i := 0; i < 10; i++ {
+		node, err := s.PlaceContainer(createConfig(0, 0), nodes)
+		assert.NoError(t, err)
+		assert.Equal(t, node.ID(), "node-1")
+	}
+}
+
+func TestSpreadPlaceContainerNew(t *testing.T) {
+	s, err := New("spread")
+	assert.NoError(t, err)
+	assert.IsType(t, s, &SpreadPlacementStrategy{})
+}
@@ -2,6 +2,7 @@ package strategy
 
 import (
 	"errors"
+	"strings"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/docker/swarm/cluster"
@@ -9,12 +10,21 @@ import (
 )
 
 type PlacementStrategy interface {
+	Name() string
+
 	Initialize() error
 	// Given a container configuration and a set of nodes, select the target
 	// node where the container should be scheduled.
 	PlaceContainer(config *dockerclient.ContainerConfig, nodes []cluster.Node) (cluster.Node, error)
 }
 
+// A strategy placing containers by weighing the nodes.
+type WeighingStrategy interface {
+	PlacementStrategy
+	// Return the weight of every node the container fits on.
+	Weigh(config *dockerclient.ContainerConfig, nodes []cluster.Node) map[cluster.Node]int64
+}
+
 var (
 	strategies      map[string]PlacementStrategy
 	ErrNotSupported = errors.New("strategy not supported")
@@ -24,10 +34,20 @@ func init() {
 	strategies = map[string]PlacementStrategy{
 		"binpacking": &BinPackingPlacementStrategy{},
 		"random":     &RandomPlacementStrategy{},
+		"spread":     &SpreadPlacementStrategy{},
 	}
 }
 
 func New(name string) (PlacementStrategy, error) {
+	if strings.HasPrefix(name, "external://") {
+		strategy, err := newExternalPlacementStrategy(name)
+		if err != nil {
+			return nil, err
+		}
+		log.WithField("name", "external").Debugf("Initializing strategy")
+		return strategy, strategy.Initialize()
+	}
+
 	if strategy, exists := strategies[name]; exists {
 		log.WithField("name", name).Debugf("Initializing strategy")
 		err := strategy.Initialize()
@@ -8,10 +8,20 @@ type weightedNode struct {
 	Node cluster.Node
 	// Weight is the inherent value of this node.
 	Weight int64
+	// Number of containers of the node, for the spread ties.
+	containers int
 }
 
 type weightedNodeList []*weightedNode
 
+func (n weightedNodeList) weights() map[cluster.Node]int64 {
+	weights := make(map[cluster.Node]int64)
+	for _, node := range n {
+		weights[node.Node] = node.Weight
+	}
+	return weights
+}
+
 func (n weightedNodeList) Len() int {
 	return len(n)
 }
@@ -28,3 +38,30 @@ func (n weightedNodeList) Less(i, j int) bool {
 
 	return ip.Weight < jp.Weight
 }
+
+// Orders the nodes by weight and, when the weights are equal, puts the node
+// running the fewest containers first.
+type spreadOrder struct {
+	weightedNodeList
+}
+
+func (n spreadOrder) Less(i, j int) bool {
+	if n.weightedNodeList[i].Weight == n.weightedNodeList[j].Weight {
+		return n.weightedNodeList[i].containers < n.weightedNodeList[j].containers
+	}
+	return n.weightedNodeList[i].Weight < n.weightedNodeList[j].Weight
+}
+
+// Nodes with a scheduling weight, such as the one given by their discovery
+// entry.
+type weighted interface {
+	Weight() int64
+}
+
+// Return the scheduling weight of `node`, 1 unless it has one.
+func nodeWeight(node cluster.Node) int64 {
+	if n, ok := node.(weighted); ok && n.Weight() > 0 {
+		return n.Weight()
+	}
+	return 1
+}
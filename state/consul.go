@@ -0,0 +1,117 @@
+package state
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/docker/swarm/discovery"
+	consul "github.com/hashicorp/consul/api"
+)
+
+// A KV stored under a consul prefix.
+type consulKV struct {
+	client *consul.Client
+	prefix string
+}
+
+func newConsulKV(uri string, options discovery.Options) (*consulKV, error) {
+	addrs, path, err := parseKVURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	config := consul.DefaultConfig()
+	config.Address = addrs[0]
+	config.Token = options.Get("token")
+	if username := options.Get("username"); username != "" {
+		config.HttpAuth = &consul.HttpBasicAuth{Username: username, Password: options.Get("password")}
+	}
+	tlsConfig, err := options.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		config.Scheme = "https"
+		config.HttpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	}
+	client, err := consul.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return &consulKV{client: client, prefix: path + "/"}, nil
+}
+
+func (s *consulKV) Get(key string) ([]byte, error) {
+	pair, _, err := s.client.KV().Get(s.prefix+key, nil)
+	if err != nil {
+		return nil, err
+	}
+	if pair == nil {
+		return nil, ErrNotFound
+	}
+	return pair.Value, nil
+}
+
+func (s *consulKV) List() (map[string][]byte, error) {
+	pairs, _, err := s.client.KV().List(s.prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte)
+	for _, pair := range pairs {
+		key := strings.TrimPrefix(pair.Key, s.prefix)
+		if key == "" {
+			continue
+		}
+		values[key] = pair.Value
+	}
+	return values, nil
+}
+
+func (s *consulKV) Create(key string, value []byte) error {
+	// A check-and-set on index 0 only writes a key that doesn't exist.
+	ok, _, err := s.client.KV().CAS(&consul.KVPair{Key: s.prefix + key, Value: value, ModifyIndex: 0}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAlreadyExists
+	}
+	return nil
+}
+
+func (s *consulKV) Update(key string, value []byte) error {
+	for {
+		pair, _, err := s.client.KV().Get(s.prefix+key, nil)
+		if err != nil {
+			return err
+		}
+		if pair == nil {
+			return ErrNotFound
+		}
+
+		// Retry if the key changed since we read it.
+		ok, _, err := s.client.KV().CAS(&consul.KVPair{Key: pair.Key, Value: value, ModifyIndex: pair.ModifyIndex}, nil)
+		if err != nil || ok {
+			return err
+		}
+	}
+}
+
+func (s *consulKV) Delete(key string) error {
+	for {
+		pair, _, err := s.client.KV().Get(s.prefix+key, nil)
+		if err != nil {
+			return err
+		}
+		if pair == nil {
+			return ErrNotFound
+		}
+
+		ok, _, err := s.client.KV().DeleteCAS(pair, nil)
+		if err != nil || ok {
+			return err
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package state
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/coreos/go-etcd/etcd"
+	"github.com/docker/swarm/discovery"
+)
+
+// A KV stored under an etcd directory.
+type etcdKV struct {
+	client *etcd.Client
+	path   string
+}
+
+func newEtcdKV(uri string, options discovery.Options) (*etcdKV, error) {
+	ips, p, err := parseKVURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := options.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	var user *url.Userinfo
+	if username := options.Get("username"); username != "" {
+		user = url.UserPassword(username, options.Get("password"))
+	}
+
+	entries := []string{}
+	for _, ip := range ips {
+		entries = append(entries, (&url.URL{Scheme: scheme, User: user, Host: ip}).String())
+	}
+	client := etcd.NewClient(entries)
+	if tlsConfig != nil {
+		client.SetTransport(&http.Transport{TLSClientConfig: tlsConfig})
+	}
+	return &etcdKV{client: client, path: "/" + p}, nil
+}
+
+// Return true if `err` is the etcd "key not found" error.
+func isEtcdNotFound(err error) bool {
+	etcdError, ok := err.(*etcd.EtcdError)
+	return ok && etcdError.ErrorCode == 100
+}
+
+// Return true if `err` is the etcd "key already exists" error.
+func isEtcdExists(err error) bool {
+	etcdError, ok := err.(*etcd.EtcdError)
+	return ok && etcdError.ErrorCode == 105
+}
+
+func (s *etcdKV) Get(key string) ([]byte, error) {
+	resp, err := s.client.Get(path.Join(s.path, key), false, false)
+	if err != nil {
+		if isEtcdNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return []byte(resp.Node.Value), nil
+}
+
+func (s *etcdKV) List() (map[string][]byte, error) {
+	values := make(map[string][]byte)
+
+	resp, err := s.client.Get(s.path, true, false)
+	if err != nil {
+		if isEtcdNotFound(err) {
+			return values, nil
+		}
+		return nil, err
+	}
+
+	for _, n := range resp.Node.Nodes {
+		if n.Dir {
+			continue
+		}
+		values[path.Base(n.Key)] = []byte(n.Value)
+	}
+	return values, nil
+}
+
+func (s *etcdKV) Create(key string, value []byte) error {
+	if _, err := s.client.Create(path.Join(s.path, key), string(value), 0); err != nil {
+		if isEtcdExists(err) {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *etcdKV) Update(key string, value []byte) error {
+	if _, err := s.client.Update(path.Join(s.path, key), string(value), 0); err != nil {
+		if isEtcdNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *etcdKV) Delete(key string) error {
+	if _, err := s.client.Delete(path.Join(s.path, key), false); err != nil {
+		if isEtcdNotFound(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
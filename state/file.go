@@ -0,0 +1,180 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// A key<->RequestedState store keeping one JSON file per key on the local disk.
+type FileStore struct {
+	RootDir string
+	values  map[string]*RequestedState
+
+	sync.RWMutex
+}
+
+func NewFileStore(rootdir string) *FileStore {
+	return &FileStore{
+		RootDir: rootdir,
+		values:  make(map[string]*RequestedState),
+	}
+}
+
+// Initialize must be called before performing any operation on the store. It
+// will attempt to restore the data from disk.
+func (s *FileStore) Initialize() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := os.MkdirAll(s.RootDir, 0700); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := s.restore(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *FileStore) path(key string) string {
+	return path.Join(s.RootDir, key+".json")
+}
+
+func (s *FileStore) restore() error {
+	files, err := ioutil.ReadDir(s.RootDir)
+	if err != nil {
+		return err
+	}
+	for _, fileinfo := range files {
+		file := fileinfo.Name()
+
+		// Verify the file extension.
+		extension := filepath.Ext(file)
+		if extension != ".json" {
+			log.Errorf("invalid file extension for filename %s (%s)", file, extension)
+			continue
+		}
+
+		// Load the object back.
+		value, err := s.load(path.Join(s.RootDir, file))
+		if err != nil {
+			log.Errorf(err.Error())
+			continue
+		}
+
+		// Extract the key.
+		key := file[0 : len(file)-len(extension)]
+		if len(key) == 0 {
+			log.Errorf("invalid filename %s", file)
+			continue
+		}
+
+		// Store it back.
+		s.values[key] = value
+	}
+	return nil
+}
+
+func (s *FileStore) load(file string) (*RequestedState, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load %s: %v", file, err)
+	}
+	value := &RequestedState{}
+	if err := json.Unmarshal(data, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Retrieves an object from the store keyed by `key`.
+func (s *FileStore) Get(key string) (*RequestedState, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if value, ok := s.values[key]; ok {
+		return value, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Return all objects of the store.
+func (s *FileStore) All() []*RequestedState {
+	s.RLock()
+	defer s.RUnlock()
+
+	states := make([]*RequestedState, len(s.values))
+	i := 0
+	for _, state := range s.values {
+		states[i] = state
+		i = i + 1
+	}
+	return states
+}
+
+func (s *FileStore) set(key string, value *RequestedState) error {
+	if len(key) == 0 {
+		return ErrInvalidKey
+	}
+
+	data, err := json.MarshalIndent(value, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(s.path(key), data, 0600); err != nil {
+		return err
+	}
+
+	s.values[key] = value
+	return nil
+}
+
+// Add a new object on the store. `key` must be unique.
+func (s *FileStore) Add(key string, value *RequestedState) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.values[key]; exists {
+		return ErrAlreadyExists
+	}
+
+	return s.set(key, value)
+}
+
+// Replaces an already existing object from the store.
+func (s *FileStore) Replace(key string, value *RequestedState) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.values[key]; !exists {
+		return ErrNotFound
+	}
+
+	return s.set(key, value)
+}
+
+// Remove `key` from the store.
+func (s *FileStore) Remove(key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.values[key]; !exists {
+		return ErrNotFound
+	}
+
+	if err := os.Remove(s.path(key)); err != nil {
+		return err
+	}
+
+	delete(s.values, key)
+	return nil
+}
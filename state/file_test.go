@@ -7,10 +7,10 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestStore(t *testing.T) {
+func TestFileStore(t *testing.T) {
 	dir, err := ioutil.TempDir("", "store-test")
 	assert.NoError(t, err)
-	store := NewStore(dir)
+	store := NewFileStore(dir)
 	assert.NoError(t, store.Initialize())
 
 	c1 := &RequestedState{}
@@ -42,7 +42,7 @@ func TestStore(t *testing.T) {
 
 	// Initialize a brand new store and retrieve "foo" again.
 	// This is to ensure data load on initialization works correctly.
-	store = NewStore(dir)
+	store = NewFileStore(dir)
 	assert.NoError(t, store.Initialize())
 	ret, err = store.Get("foo")
 	assert.NoError(t, err)
@@ -0,0 +1,188 @@
+package state
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// KV is a flat key/value store holding the serialized states.
+type KV interface {
+	// Return the value of `key`, ErrNotFound if it doesn't exist.
+	Get(key string) ([]byte, error)
+
+	// Return all the values, keyed by key.
+	List() (map[string][]byte, error)
+
+	// Create `key` with `value`, ErrAlreadyExists if it exists. The check
+	// and the write must be a single atomic operation of the backend.
+	Create(key string, value []byte) error
+
+	// Set the value of an existing `key`, ErrNotFound if it doesn't exist.
+	Update(key string, value []byte) error
+
+	// Delete an existing `key`, ErrNotFound if it doesn't exist.
+	Delete(key string) error
+}
+
+// A key<->RequestedState store backed by a KV, which can be shared by several
+// managers: the KV makes the writes atomic across all of them.
+type KVStore struct {
+	kv KV
+}
+
+func NewKVStore(kv KV) *KVStore {
+	return &KVStore{kv: kv}
+}
+
+func (s *KVStore) Initialize() error {
+	// Make sure the store can be reached.
+	_, err := s.kv.List()
+	return err
+}
+
+func (s *KVStore) Get(key string) (*RequestedState, error) {
+	// An empty key would be the root of the store.
+	if len(key) == 0 {
+		return nil, ErrInvalidKey
+	}
+
+	data, err := s.kv.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	value := &RequestedState{}
+	if err := json.Unmarshal(data, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (s *KVStore) All() []*RequestedState {
+	values, err := s.kv.List()
+	if err != nil {
+		log.Errorf("unable to list the store: %v", err)
+		return []*RequestedState{}
+	}
+
+	states := []*RequestedState{}
+	for key, data := range values {
+		value := &RequestedState{}
+		if err := json.Unmarshal(data, value); err != nil {
+			log.Errorf("unable to load %s: %v", key, err)
+			continue
+		}
+		states = append(states, value)
+	}
+	return states
+}
+
+func (s *KVStore) marshal(key string, value *RequestedState) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, ErrInvalidKey
+	}
+	return json.Marshal(value)
+}
+
+func (s *KVStore) Add(key string, value *RequestedState) error {
+	data, err := s.marshal(key, value)
+	if err != nil {
+		return err
+	}
+	return s.kv.Create(key, data)
+}
+
+func (s *KVStore) Replace(key string, value *RequestedState) error {
+	data, err := s.marshal(key, value)
+	if err != nil {
+		return err
+	}
+	return s.kv.Update(key, data)
+}
+
+func (s *KVStore) Remove(key string) error {
+	if len(key) == 0 {
+		return ErrInvalidKey
+	}
+	return s.kv.Delete(key)
+}
+
+// MemoryKV is an in-memory KV, for tests.
+type MemoryKV struct {
+	values map[string][]byte
+
+	sync.RWMutex
+}
+
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{values: make(map[string][]byte)}
+}
+
+func (m *MemoryKV) Get(key string) ([]byte, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	if value, exists := m.values[key]; exists {
+		return value, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryKV) List() (map[string][]byte, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	values := make(map[string][]byte)
+	for key, value := range m.values {
+		values[key] = value
+	}
+	return values, nil
+}
+
+func (m *MemoryKV) Create(key string, value []byte) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, exists := m.values[key]; exists {
+		return ErrAlreadyExists
+	}
+	m.values[key] = value
+	return nil
+}
+
+func (m *MemoryKV) Update(key string, value []byte) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, exists := m.values[key]; !exists {
+		return ErrNotFound
+	}
+	m.values[key] = value
+	return nil
+}
+
+func (m *MemoryKV) Delete(key string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, exists := m.values[key]; !exists {
+		return ErrNotFound
+	}
+	delete(m.values, key)
+	return nil
+}
+
+// Keys returns the sorted keys of the store.
+func (m *MemoryKV) Keys() []string {
+	m.RLock()
+	defer m.RUnlock()
+
+	keys := []string{}
+	for key := range m.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -4,8 +4,26 @@ import (
 	"github.com/samalba/dockerclient"
 )
 
+const (
+	// ModeGlobal containers run a copy on every node accepted by the filters.
+	ModeGlobal = "global"
+)
+
 type RequestedState struct {
-	ID     string
-	Name   string
-	Config *dockerclient.ContainerConfig
+	// ID is the cluster-wide ID, it survives rescheduling.
+	ID string
+	// EngineID is the ID of the container on its current node.
+	EngineID string
+	Name     string
+	Config   *dockerclient.ContainerConfig
+	Mode     string
+}
+
+// Return the ID of the container on its current node. States saved before
+// cluster-wide IDs existed only know the engine ID.
+func (s *RequestedState) Engine() string {
+	if s.EngineID != "" {
+		return s.EngineID
+	}
+	return s.ID
 }
@@ -1,187 +1,117 @@
 package state
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path"
-	"path/filepath"
-	"sync"
+	"strings"
 
-	log "github.com/Sirupsen/logrus"
+	"github.com/docker/swarm/discovery"
 )
 
 var (
 	ErrNotFound      = errors.New("not found")
 	ErrAlreadyExists = errors.New("already exists")
 	ErrInvalidKey    = errors.New("invalid key")
+	ErrNotSupported  = errors.New("store not supported")
 )
 
-// A simple key<->RequestedState store.
-type Store struct {
-	RootDir string
-	values  map[string]*RequestedState
+// A key<->RequestedState store.
+type Store interface {
+	// Initialize must be called before performing any operation on the store.
+	Initialize() error
 
-	sync.RWMutex
-}
-
-func NewStore(rootdir string) *Store {
-	return &Store{
-		RootDir: rootdir,
-		values:  make(map[string]*RequestedState),
-	}
-}
-
-// Initialize must be called before performing any operation on the store. It
-// will attempt to restore the data from disk.
-func (s *Store) Initialize() error {
-	s.Lock()
-	defer s.Unlock()
+	// Retrieves an object from the store keyed by `key`.
+	Get(key string) (*RequestedState, error)
 
-	if err := os.MkdirAll(s.RootDir, 0700); err != nil && !os.IsNotExist(err) {
-		return err
-	}
+	// Return all objects of the store.
+	All() []*RequestedState
 
-	if err := s.restore(); err != nil {
-		return err
-	}
+	// Add a new object on the store. `key` must be unique.
+	Add(key string, value *RequestedState) error
 
-	return nil
-}
+	// Replaces an already existing object from the store.
+	Replace(key string, value *RequestedState) error
 
-func (s *Store) path(key string) string {
-	return path.Join(s.RootDir, key+".json")
+	// Remove `key` from the store.
+	Remove(key string) error
 }
 
-func (s *Store) restore() error {
-	files, err := ioutil.ReadDir(s.RootDir)
-	if err != nil {
-		return err
+// New returns the store for `uri`. Key/value stores are selected with
+// `consul://`, `etcd://` or `zk://` URIs, which take the TLS and
+// authentication options of the discovery services, anything else is a
+// local directory.
+func New(uri string) (Store, error) {
+	parts := strings.SplitN(uri, "://", 2)
+	if len(parts) == 1 {
+		return NewFileStore(uri), nil
 	}
-	for _, fileinfo := range files {
-		file := fileinfo.Name()
-
-		// Verify the file extension.
-		extension := filepath.Ext(file)
-		if extension != ".json" {
-			log.Errorf("invalid file extension for filename %s (%s)", file, extension)
-			continue
-		}
-
-		// Load the object back.
-		value, err := s.load(path.Join(s.RootDir, file))
-		if err != nil {
-			log.Errorf(err.Error())
-			continue
-		}
-
-		// Extract the key.
-		key := file[0 : len(file)-len(extension)]
-		if len(key) == 0 {
-			log.Errorf("invalid filename %s", file)
-			continue
-		}
-
-		// Store it back.
-		s.values[key] = value
+	if parts[0] == "file" {
+		return NewFileStore(parts[1]), nil
 	}
-	return nil
-}
 
-func (s *Store) load(file string) (*RequestedState, error) {
-	data, err := ioutil.ReadFile(file)
+	rest, options, err := discovery.ParseOptions(parts[1])
 	if err != nil {
-		return nil, fmt.Errorf("unable to load %s: %v", file, err)
-	}
-	value := &RequestedState{}
-	if err := json.Unmarshal(data, value); err != nil {
 		return nil, err
 	}
-	return value, nil
-}
-
-// Retrieves an object from the store keyed by `key`.
-func (s *Store) Get(key string) (*RequestedState, error) {
-	s.RLock()
-	defer s.RUnlock()
-
-	if value, ok := s.values[key]; ok {
-		return value, nil
+	var kv KV
+	switch parts[0] {
+	case "consul":
+		kv, err = newConsulKV(rest, options)
+	case "etcd":
+		kv, err = newEtcdKV(rest, options)
+	case "zk":
+		kv, err = newZkKV(rest, options)
+	default:
+		return nil, ErrNotSupported
 	}
-	return nil, ErrNotFound
-}
-
-// Return all objects of the store.
-func (s *Store) All() []*RequestedState {
-	s.RLock()
-	defer s.RUnlock()
-
-	states := make([]*RequestedState, len(s.values))
-	i := 0
-	for _, state := range s.values {
-		states[i] = state
-		i = i + 1
+	if err != nil {
+		return nil, err
 	}
-	return states
+	return NewKVStore(kv), nil
 }
 
-func (s *Store) set(key string, value *RequestedState) error {
-	if len(key) == 0 {
-		return ErrInvalidKey
+// Split `<ip1>,<ip2>/<path>` into the addresses and the path.
+func parseKVURI(uri string) ([]string, string, error) {
+	parts := strings.SplitN(uri, "/", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, "", fmt.Errorf("invalid format %q, missing <path>", uri)
 	}
+	return strings.Split(parts[0], ","), strings.Trim(parts[1], "/"), nil
+}
 
-	data, err := json.MarshalIndent(value, "", "    ")
+// Same as parseKVURI, ignoring the options of `uri`.
+func parseBareKVURI(uri string) ([]string, string, error) {
+	uri, _, err := discovery.ParseOptions(uri)
 	if err != nil {
-		return err
-	}
-
-	if err := ioutil.WriteFile(s.path(key), data, 0600); err != nil {
-		return err
+		return nil, "", err
 	}
-
-	s.values[key] = value
-	return nil
+	return parseKVURI(uri)
 }
 
-// Add a new object on the store. `key` must be unique.
-func (s *Store) Add(key string, value *RequestedState) error {
-	s.Lock()
-	defer s.Unlock()
-
-	if _, exists := s.values[key]; exists {
-		return ErrAlreadyExists
+// Overlaps returns whether the key/value store `uri` and the discovery
+// `discovery` share the same backend and nested paths, such as
+// `consul://<ip>/swarm/state` under `consul://<ip>/swarm`: the discovery
+// would then read the states as entries, and the store the entries as states.
+// The addresses and the options aren't compared, as several can reach the
+// same backend.
+func Overlaps(uri, dflag string) bool {
+	a, b := strings.SplitN(uri, "://", 2), strings.SplitN(dflag, "://", 2)
+	if len(a) != 2 || len(b) != 2 || a[0] != b[0] {
+		return false
 	}
-
-	return s.set(key, value)
-}
-
-// Replaces an already existing object from the store.
-func (s *Store) Replace(key string, value *RequestedState) error {
-	s.Lock()
-	defer s.Unlock()
-
-	if _, exists := s.values[key]; !exists {
-		return ErrNotFound
+	switch a[0] {
+	case "consul", "etcd", "zk":
+	default:
+		return false
 	}
 
-	return s.set(key, value)
-}
-
-// Remove `key` from the store.
-func (s *Store) Remove(key string) error {
-	s.Lock()
-	defer s.Unlock()
-
-	if _, exists := s.values[key]; !exists {
-		return ErrNotFound
+	_, p, err := parseBareKVURI(a[1])
+	if err != nil {
+		return false
 	}
-
-	if err := os.Remove(s.path(key)); err != nil {
-		return err
+	_, q, err := parseBareKVURI(b[1])
+	if err != nil {
+		return false
 	}
-
-	delete(s.values, key)
-	return nil
+	return p == q || strings.HasPrefix(p, q+"/") || strings.HasPrefix(q, p+"/")
 }
@@ -0,0 +1,116 @@
+package state
+
+import (
+	"crypto/tls"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/docker/swarm/discovery"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// A KV stored as children of a zookeeper node.
+type zkKV struct {
+	conn *zk.Conn
+	path string
+	acl  []zk.ACL
+}
+
+func newZkKV(uri string, options discovery.Options) (*zkKV, error) {
+	ips, p, err := parseKVURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := options.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	dialer := net.DialTimeout
+	if tlsConfig != nil {
+		dialer = func(network, address string, timeout time.Duration) (net.Conn, error) {
+			return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, network, address, tlsConfig)
+		}
+	}
+
+	conn, _, err := zk.ConnectWithDialer(ips, time.Second, dialer)
+	if err != nil {
+		return nil, err
+	}
+
+	// With credentials, the states can only be read and changed by us.
+	s := &zkKV{conn: conn, path: "/" + p, acl: zk.WorldACL(zk.PermAll)}
+	if username := options.Get("username"); username != "" {
+		if err := conn.AddAuth("digest", []byte(username+":"+options.Get("password"))); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		s.acl = zk.DigestACL(zk.PermAll, username, options.Get("password"))
+	}
+	return s, s.createFullpath()
+}
+
+func (s *zkKV) createFullpath() error {
+	parts := strings.Split(strings.Trim(s.path, "/"), "/")
+	for i := 1; i <= len(parts); i++ {
+		newpath := "/" + strings.Join(parts[:i], "/")
+		if _, err := s.conn.Create(newpath, []byte{1}, 0, s.acl); err != nil && err != zk.ErrNodeExists {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *zkKV) Get(key string) ([]byte, error) {
+	data, _, err := s.conn.Get(s.path + "/" + key)
+	if err == zk.ErrNoNode {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *zkKV) List() (map[string][]byte, error) {
+	keys, _, err := s.conn.Children(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte)
+	for _, key := range keys {
+		data, err := s.Get(key)
+		if err == ErrNotFound {
+			// Removed in the meantime.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[key] = data
+	}
+	return values, nil
+}
+
+func (s *zkKV) Create(key string, value []byte) error {
+	_, err := s.conn.Create(s.path+"/"+key, value, 0, s.acl)
+	if err == zk.ErrNodeExists {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func (s *zkKV) Update(key string, value []byte) error {
+	_, err := s.conn.Set(s.path+"/"+key, value, -1)
+	if err == zk.ErrNoNode {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *zkKV) Delete(key string) error {
+	err := s.conn.Delete(s.path+"/"+key, -1)
+	if err == zk.ErrNoNode {
+		return ErrNotFound
+	}
+	return err
+}